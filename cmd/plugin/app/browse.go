@@ -0,0 +1,207 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+
+	"github.com/fatsheep9146/kubectl-pvc/pkg/plugin"
+)
+
+var (
+	browseExample = `
+	# open a shell in a helper pod which mounts the pvc read-only at /data
+	kubectl pvc browse <pvc> -n <namespace>
+
+	# list the files in the pvc, or show the disk usage of each directory
+	kubectl pvc browse <pvc> -n <namespace> --ls
+	kubectl pvc browse <pvc> -n <namespace> --du
+
+	# run any command in /data, with the pvc mounted read-write
+	kubectl pvc browse <pvc> -n <namespace> --rw -- rm -rf cache
+`
+)
+
+type BrowseOption struct {
+	opt  plugin.BrowseOption
+	ls   bool
+	du   bool
+	pctx *plugin.PvcContext
+}
+
+func NewBrowseOption() *BrowseOption {
+	return &BrowseOption{}
+}
+
+func NewBrowseCommand() *cobra.Command {
+	opts := NewBrowseOption()
+
+	cmd := &cobra.Command{
+		Use:     "browse [namespace/]pvc [-- command...]",
+		Aliases: []string{"shell"},
+		Short:   "open a shell or run a command in a helper pod which mounts the pvc",
+		Example: browseExample,
+		// exit code of the command in helper pod should not be mixed with usage
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(pctx); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(args); err != nil {
+				return err
+			}
+
+			if err := opts.Run(args); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.ls, "ls", false, "list the files in the pvc instead of opening a shell")
+	cmd.Flags().BoolVar(&opts.du, "du", false, "show the disk usage of each directory in the pvc instead of opening a shell")
+	cmd.Flags().BoolVar(&opts.opt.ReadWrite, "rw", false, "mount the pvc read-write, it is mounted read-only by default")
+	cmd.Flags().StringVar(&opts.opt.Image, "image", plugin.DefaultBrowseImage, "the image of helper pod, it should contain a shell")
+	cmd.Flags().DurationVar(&opts.opt.MaxLifetime, "max-lifetime", time.Hour, "the helper pod is killed after this duration in case it is left behind")
+	cmd.Flags().DurationVar(&opts.opt.Timeout, "timeout", 2*time.Minute, "give up waiting for the helper pod to run after this duration, zero means waiting forever")
+	return cmd
+}
+
+func (opts *BrowseOption) Complete(pctx *plugin.PvcContext) error {
+	opts.pctx = pctx
+	return nil
+}
+
+func (opts *BrowseOption) Validate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("user should input one pvc to browse")
+	}
+	if opts.ls && opts.du {
+		return fmt.Errorf("--ls and --du can not be used together")
+	}
+	if (opts.ls || opts.du) && len(args) > 1 {
+		return fmt.Errorf("--ls and --du can not be used together with a command")
+	}
+	if opts.opt.MaxLifetime < time.Minute {
+		return fmt.Errorf("--max-lifetime should be at least 1m")
+	}
+	return nil
+}
+
+func (opts *BrowseOption) Run(args []string) error {
+	command := args[1:]
+	switch {
+	case opts.ls:
+		command = []string{"ls", "-la", plugin.BrowseMountPath}
+	case opts.du:
+		command = []string{"du", "-h", "-d", "1", plugin.BrowseMountPath}
+	}
+	interactive := len(command) == 0
+	if interactive {
+		command = []string{"sh"}
+	}
+
+	namespace, name := parsePvcArg(args[0])
+	pod, err := opts.pctx.StartBrowseHelper(namespace, name, opts.opt)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := opts.pctx.DeleteBrowseHelper(pod); err != nil {
+			fmt.Fprintf(os.Stderr, "%v, it is killed after %v\n", err, opts.opt.MaxLifetime)
+		}
+	}()
+
+	if interactive {
+		mode := "read-only"
+		if opts.opt.ReadWrite {
+			mode = "read-write"
+		}
+		fmt.Fprintf(os.Stderr, "pvc %s is mounted %s at %s of pod %s, exit the shell to delete the pod\n", name, mode, plugin.BrowseMountPath, pod.Name)
+	}
+
+	streams := remotecommand.StreamOptions{Stdout: os.Stdout, Stderr: os.Stderr}
+	if interactive {
+		// the shell reads commands from stdin, a tty is allocated only if stdin is a terminal
+		streams.Stdin = os.Stdin
+		fd := int(os.Stdin.Fd())
+		if terminal.IsTerminal(fd) {
+			state, err := terminal.MakeRaw(fd)
+			if err != nil {
+				return fmt.Errorf("make terminal raw failed, err: %v", err)
+			}
+			defer terminal.Restore(fd, state)
+
+			sizes := newTerminalSizeQueue(fd)
+			defer sizes.stop()
+			// stderr is merged into stdout by tty
+			streams.Stderr = nil
+			streams.Tty = true
+			streams.TerminalSizeQueue = sizes
+		}
+	}
+
+	// the plugin should live on to delete the helper pod when it is interrupted
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- opts.pctx.ExecBrowseHelper(pod, command, streams)
+	}()
+	select {
+	case err = <-done:
+	case <-interrupts:
+		return &ExitError{Code: 130, Err: fmt.Errorf("interrupted, command in pod %s is stopped", pod.Name)}
+	}
+
+	if e, ok := err.(utilexec.ExitError); ok {
+		return &ExitError{Code: e.ExitStatus(), Err: fmt.Errorf("command in pod %s exited with code %d", pod.Name, e.ExitStatus())}
+	}
+	return err
+}
+
+// terminalSizeQueue reports the size of local terminal to the tty of helper pod, it polls the size instead
+// of waiting for SIGWINCH which is not available on every platform
+type terminalSizeQueue struct {
+	fd    int
+	last  remotecommand.TerminalSize
+	stopc chan struct{}
+}
+
+func newTerminalSizeQueue(fd int) *terminalSizeQueue {
+	return &terminalSizeQueue{fd: fd, stopc: make(chan struct{})}
+}
+
+// Next returns the size when it is changed, nil is returned after the queue is stopped
+func (q *terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		width, height, err := terminal.GetSize(q.fd)
+		if err == nil {
+			size := remotecommand.TerminalSize{Width: uint16(width), Height: uint16(height)}
+			if size != q.last {
+				q.last = size
+				return &size
+			}
+		}
+		select {
+		case <-q.stopc:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *terminalSizeQueue) stop() {
+	close(q.stopc)
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fatsheep9146/kubectl-pvc/pkg/plugin"
+)
+
+var (
+	cloneExample = `
+	# clone the pvc into a new pvc with the same storageclass, access modes, volume mode and size
+	kubectl pvc clone <src-pvc> <dst-pvc> -n <namespace>
+
+	# clone the pvc into a larger pvc, and do not wait until it is bound
+	kubectl pvc clone <src-pvc> <dst-pvc> -n <namespace> --size 100Gi --wait=false
+`
+)
+
+type CloneOption struct {
+	overrides plugin.PvcOverrides
+	wait      bool
+	timeout   time.Duration
+	pctx      *plugin.PvcContext
+}
+
+func NewCloneOption() *CloneOption {
+	return &CloneOption{}
+}
+
+func NewCloneCommand() *cobra.Command {
+	opts := NewCloneOption()
+
+	cmd := &cobra.Command{
+		Use:     "clone [namespace/]src-pvc [namespace/]dst-pvc",
+		Short:   "clone one pvc into a new pvc in the same namespace",
+		Example: cloneExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(pctx); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			if err := opts.Run(args); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	addPvcOverridesFlags(cmd, &opts.overrides)
+	cmd.Flags().BoolVar(&opts.wait, "wait", true, "follow the provision and binding of new pvc until it is bound, or until it waits for the first consumer")
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", 5*time.Minute, "give up waiting after this duration, zero means waiting forever, only works with --wait")
+	return cmd
+}
+
+func (opts *CloneOption) Complete(pctx *plugin.PvcContext) error {
+	opts.pctx = pctx
+	return nil
+}
+
+func (opts *CloneOption) Validate() error {
+	return opts.overrides.Validate()
+}
+
+func (opts *CloneOption) Run(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("user should input the source pvc and the name of new pvc")
+	}
+
+	srcNamespace, src := parsePvcArg(args[0])
+	dstNamespace, dst := parsePvcArg(args[1])
+	if srcNamespace == "" {
+		srcNamespace = opts.pctx.GetNamespace()
+	}
+	if dstNamespace == "" {
+		dstNamespace = srcNamespace
+	}
+	if srcNamespace != dstNamespace {
+		return fmt.Errorf("pvc can only be cloned in the same namespace, but source is in %s and destination is in %s", srcNamespace, dstNamespace)
+	}
+
+	pvc, err := opts.pctx.ClonePvc(srcNamespace, src, dst, opts.overrides)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "persistentvolumeclaim/%s created from persistentvolumeclaim/%s\n", pvc.Name, src)
+	if !opts.wait {
+		return nil
+	}
+
+	err = opts.pctx.WaitPvcPhase(pvc.Namespace, pvc.Name, plugin.PvcBind, opts.timeout, func(name plugin.PvcPhaseName, status plugin.PvcPhaseStatus, detail string) {
+		fmt.Fprintf(os.Stdout, "%s   %-10s %-14s %s\n", time.Now().Format("15:04:05"), name, status, detail)
+	})
+	if err == plugin.ErrWaitForFirstConsumer {
+		fmt.Fprintf(os.Stdout, "persistentvolumeclaim/%s is waiting for first consumer, it is provisioned and bound once a pod using it is scheduled\n", pvc.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "persistentvolumeclaim/%s is bound\n", pvc.Name)
+	return nil
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fatsheep9146/kubectl-pvc/pkg/plugin"
+)
+
+var (
+	dfExample = `
+	# check the filesystem usage of all mounted pvcs of given namespace
+	kubectl pvc df -n <namespace>
+
+	# check the filesystem usage of pvcs of all namespaces, the most used first
+	kubectl pvc df -A
+
+	# check the filesystem usage of all pvcs of given pod, sorted by used inodes
+	kubectl pvc df -n <namespace> -p <pod> --sort-by inodes
+`
+)
+
+type DfOption struct {
+	opt    plugin.GetPvcsUsageOption
+	output string
+	pctx   *plugin.PvcContext
+}
+
+func NewDfOption() *DfOption {
+	return &DfOption{}
+}
+
+func NewDfCommand() *cobra.Command {
+	opts := NewDfOption()
+
+	cmd := &cobra.Command{
+		Use:     "df",
+		Short:   "show the filesystem usage of pvcs mounted by pods",
+		Example: dfExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(pctx); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			if err := opts.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.opt.Pod, "pod", "p", "", "only show the pvcs of the specific pod")
+	cmd.Flags().BoolVarP(&opts.opt.AllNamespaces, "all-namespaces", "A", false, "show pvcs of all namespaces")
+	cmd.Flags().StringVar(&opts.opt.SortBy, "sort-by", plugin.SortByPercent, "sort pvcs from the most used, one of: percent|used|inodes")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "output format, one of: json|yaml")
+	return cmd
+}
+
+func (opts *DfOption) Complete(pctx *plugin.PvcContext) error {
+	opts.pctx = pctx
+	return nil
+}
+
+func (opts *DfOption) Validate() error {
+	if opts.opt.AllNamespaces && opts.opt.Pod != "" {
+		return fmt.Errorf("--pod can not be used together with --all-namespaces")
+	}
+	switch opts.opt.SortBy {
+	case plugin.SortByPercent, plugin.SortByUsed, plugin.SortByInodes:
+	default:
+		return fmt.Errorf("unsupported --sort-by %q, should be one of: percent|used|inodes", opts.opt.SortBy)
+	}
+	switch opts.output {
+	case "", plugin.OutputJSON, plugin.OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q, allowed formats are: json,yaml", opts.output)
+}
+
+func (opts *DfOption) Run() error {
+	usages, err := opts.pctx.GetPvcsUsage(opts.opt)
+	if err != nil {
+		return err
+	}
+
+	if opts.output != "" {
+		return plugin.PrintPvcsUsage(os.Stdout, usages, opts.output)
+	}
+
+	plugin.FormatPvcsUsage(os.Stdout, usages, opts.opt.AllNamespaces)
+	return nil
+}
@@ -3,6 +3,8 @@ package app
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -11,12 +13,29 @@ import (
 
 var (
 	inspectExample = `
-	
+	# check the status of pvc in every phase
+	kubectl pvc inspect <pvc> -n <namespace>
+
+	# the namespace can also be given together with the name of pvc
+	kubectl pvc inspect <namespace>/<pvc>
+
+	# check the status together with all related events
+	kubectl pvc inspect <pvc> -n <namespace> --events
+
+	# follow the phase transitions of pvc until all phases succeed, or give up after 5 minutes
+	kubectl pvc inspect <pvc> -n <namespace> --watch --timeout 5m
+
+	# print the status in json for scripts
+	kubectl pvc inspect <pvc> -n <namespace> -o json
 `
 )
 
 type InspectOption struct {
 	pvcname string
+	events  bool
+	output  string
+	watch   bool
+	timeout time.Duration
 	pctx    *plugin.PvcContext
 }
 
@@ -28,7 +47,7 @@ func NewInspectCommand() *cobra.Command {
 	opts := NewInspectOption()
 
 	cmd := &cobra.Command{
-		Use:     "inspect",
+		Use:     "inspect [namespace/]pvc",
 		Short:   "inspect one specific pvc status",
 		Example: inspectExample,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -46,6 +65,11 @@ func NewInspectCommand() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&opts.events, "events", false, "print all events related to the pvc")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "output format, one of: json|yaml|wide")
+	cmd.Flags().BoolVarP(&opts.watch, "watch", "w", false, "follow the changes of pvc and related objects until all phases succeed")
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", 0, "give up watching after this duration, zero means never, only works with --watch")
 	return cmd
 }
 
@@ -55,7 +79,14 @@ func (opts *InspectOption) Complete(pctx *plugin.PvcContext) error {
 }
 
 func (opts *InspectOption) Validate() error {
-	return nil
+	if opts.watch && (opts.output == plugin.OutputJSON || opts.output == plugin.OutputYAML) {
+		return fmt.Errorf("--watch can not be used together with output format %s", opts.output)
+	}
+	switch opts.output {
+	case "", plugin.OutputWide, plugin.OutputJSON, plugin.OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q, allowed formats are: json,yaml,wide", opts.output)
 }
 
 func (opts *InspectOption) Run(args []string) (err error) {
@@ -63,12 +94,64 @@ func (opts *InspectOption) Run(args []string) (err error) {
 		return fmt.Errorf("user should input one pvc to inspect")
 	}
 
-	pvcStatus, err := opts.pctx.GetPvcDetail(args[0])
+	namespace, name := parsePvcArg(args[0])
+	if opts.watch {
+		return opts.runWatch(namespace, name)
+	}
+
+	pvcStatus, err := opts.pctx.GetPvcDetail(namespace, name)
 	if err != nil {
 		return err
 	}
 
-	plugin.FormatPvcDetail(os.Stdout, pvcStatus)
+	if opts.output == plugin.OutputJSON || opts.output == plugin.OutputYAML {
+		return plugin.PrintPvcStatus(os.Stdout, pvcStatus, opts.output)
+	}
+
+	plugin.FormatPvcDetail(os.Stdout, pvcStatus, opts.output == plugin.OutputWide)
+	if opts.events {
+		plugin.FormatPvcEvents(os.Stdout, pvcStatus)
+	}
 
 	return nil
 }
+
+// runWatch prints the rows of status changed since last time, until all phases succeed or timeout expires
+func (opts *InspectOption) runWatch(namespace, name string) error {
+	var (
+		last    *plugin.PvcStatus
+		lastErr string
+	)
+	fmt.Fprintf(os.Stdout, "TIME       %-24s %-14s %s\n", "OBJECT", "STATUS", "DETAIL")
+	err := opts.pctx.WatchPvcDetail(namespace, name, opts.timeout, func(status *plugin.PvcStatus, err error) bool {
+		now := time.Now()
+		if err != nil {
+			// the pvc may be not created yet, keep watching
+			if err.Error() != lastErr {
+				fmt.Fprintf(os.Stdout, "%s   %-24s %-14s %v\n", now.Format("15:04:05"), "pvc/"+name, "error", err)
+				lastErr = err.Error()
+			}
+			return false
+		}
+		lastErr = ""
+		plugin.FormatPvcStatusChanges(os.Stdout, last, status, now)
+		last = status
+		if plugin.IsPvcHealthy(status) {
+			fmt.Fprintln(os.Stdout, "all phases succeeded")
+			return true
+		}
+		return false
+	})
+	if err == plugin.ErrTimeout {
+		return fmt.Errorf("not all phases of pvc %s succeeded within %v", name, opts.timeout)
+	}
+	return err
+}
+
+// parsePvcArg splits namespace/name, namespace is empty if it is not given
+func parsePvcArg(arg string) (namespace, name string) {
+	if i := strings.Index(arg, "/"); i >= 0 {
+		return arg[:i], arg[i+1:]
+	}
+	return "", arg
+}
@@ -3,9 +3,13 @@ package app
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/genericclioptions/printers"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog"
 
 	"github.com/fatsheep9146/kubectl-pvc/pkg/plugin"
@@ -16,18 +20,46 @@ var (
 	# check all pvcs of given namespace
 	kubectl pvc ls -n <namespace>
 
+	# check all pvcs of all namespaces
+	kubectl pvc ls -A
+
+	# check all pvcs with given labels which are not healthy
+	kubectl pvc ls -n <namespace> -l app=foo --unhealthy
+
+	# check all pending pvcs of given storageclass
+	kubectl pvc ls -A --storage-class <storageclass> --phase Pending
+
 	# check all pvcs of given pod
 	kubectl pvc ls -n <namespace> -p <pod>
+
+	# check all pvcs expected by given workload, and which of them are missing or unbound
+	kubectl pvc ls -n <namespace> --owner statefulset/<statefulset>
+	kubectl pvc ls -n <namespace> --owner deployment/<deployment>
+
+	# print the names of all pvcs of given pod for other tools
+	kubectl pvc ls -n <namespace> -p <pod> -o name
+
+	# print pvcs with custom columns
+	kubectl pvc ls -n <namespace> -o custom-columns=NAME:.metadata.name,SIZE:.spec.resources.requests.storage
 `
 )
 
 type LsOption struct {
-	podname string
-	pctx    *plugin.PvcContext
+	podname       string
+	owner         string
+	allNamespaces bool
+	labelSelector string
+	fieldSelector string
+	filter        plugin.PvcFilter
+	printFlags    *genericclioptions.PrintFlags
+	printer       printers.ResourcePrinter
+	pctx          *plugin.PvcContext
 }
 
 func NewLsOption() *LsOption {
-	return &LsOption{}
+	return &LsOption{
+		printFlags: genericclioptions.NewPrintFlags("").WithTypeSetter(scheme.Scheme),
+	}
 }
 
 func NewLsCommand() *cobra.Command {
@@ -35,7 +67,7 @@ func NewLsCommand() *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:     "ls",
-		Short:   "list all pvcs of the whole namespace or one pod",
+		Short:   "list all pvcs of the whole namespace, one pod or one workload",
 		Example: lsExample,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := opts.Complete(pctx); err != nil {
@@ -54,6 +86,21 @@ func NewLsCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&opts.podname, "pod", "p", "", "the specific pod name you want to check")
+	cmd.Flags().StringVar(&opts.owner, "owner", "", "the workload whose pvcs you want to check, e.g. deployment/foo, one of kinds: deployment|statefulset|daemonset|replicaset|job|cronjob")
+	cmd.Flags().BoolVarP(&opts.allNamespaces, "all-namespaces", "A", false, "list pvcs of all namespaces")
+	cmd.Flags().StringVarP(&opts.labelSelector, "selector", "l", "", "label selector to filter pvcs, e.g. -l key1=value1,key2=value2")
+	cmd.Flags().StringVar(&opts.fieldSelector, "field-selector", "", "field selector to filter pvcs, e.g. --field-selector metadata.name=foo")
+	cmd.Flags().StringVar(&opts.filter.StorageClass, "storage-class", "", "only list pvcs of the storageclass")
+	cmd.Flags().StringVar(&opts.filter.Phase, "phase", "", "only list pvcs in the phase, one of: Pending|Bound|Lost")
+	cmd.Flags().BoolVar(&opts.filter.Unbound, "unbound", false, "only list pvcs which are not bound to any pv")
+	cmd.Flags().StringVar(&opts.filter.AccessMode, "access-mode", "", "only list pvcs with the access mode, e.g. RWO or ReadWriteOnce")
+	cmd.Flags().BoolVar(&opts.filter.Unhealthy, "unhealthy", false, "only list pvcs with any lifecycle phase not successful")
+	cmd.Flags().StringVarP(opts.printFlags.OutputFormat, "output", "o", "", fmt.Sprintf("output format, one of: %s",
+		strings.Join(append(opts.printFlags.AllowedFormats(), plugin.OutputWide, plugin.OutputCustomColumns+"="), "|")))
+	opts.printFlags.OutputFlagSpecified = func() bool {
+		return cmd.Flag("output").Changed
+	}
+	opts.printFlags.TemplatePrinterFlags.AddFlags(cmd)
 	return cmd
 }
 
@@ -62,8 +109,34 @@ func (opts *LsOption) Complete(pctx *plugin.PvcContext) error {
 	return nil
 }
 
-func (opts *LsOption) Validate() error {
-	return nil
+func (opts *LsOption) Validate() (err error) {
+	if opts.allNamespaces && opts.podname != "" {
+		return fmt.Errorf("--pod can not be used together with --all-namespaces")
+	}
+	if opts.owner != "" {
+		if opts.allNamespaces || opts.podname != "" {
+			return fmt.Errorf("--owner can not be used together with --pod or --all-namespaces")
+		}
+		if _, _, err := plugin.ParseOwner(opts.owner); err != nil {
+			return err
+		}
+	}
+	if err := opts.filter.Validate(); err != nil {
+		return err
+	}
+
+	output := *opts.printFlags.OutputFormat
+	switch {
+	case output == "" || output == plugin.OutputWide:
+		// printed as table by plugin.Format
+		return nil
+	case strings.HasPrefix(output, plugin.OutputCustomColumns+"="):
+		opts.printer, err = plugin.NewCustomColumnsPrinter(strings.TrimPrefix(output, plugin.OutputCustomColumns+"="))
+		return err
+	}
+
+	opts.printer, err = opts.printFlags.ToPrinter()
+	return err
 }
 
 func (opts *LsOption) Run() (err error) {
@@ -74,20 +147,57 @@ func (opts *LsOption) Run() (err error) {
 
 	pctx := opts.pctx
 	pvcs := make([]v1.PersistentVolumeClaim, 0)
+	listOpt := plugin.ListPvcsOption{
+		AllNamespaces: opts.allNamespaces,
+		LabelSelector: opts.labelSelector,
+		FieldSelector: opts.fieldSelector,
+	}
 
-	if opts.podname == "" {
-		pvcs, err = pctx.ListPvcs()
+	var claims []*plugin.WorkloadClaim
+	switch {
+	case opts.owner != "":
+		pvcs, claims, err = pctx.ListPvcsByOwner(opts.owner, listOpt)
+		if err != nil {
+			klog.Errorf("list pvcs of %v in namespace %v failed, err %v", opts.owner, pctx.GetNamespace(), err)
+		}
+	case opts.podname == "":
+		pvcs, err = pctx.ListPvcs(listOpt)
 		if err != nil {
 			klog.Errorf("list pvcs of namespace %v failed, err %v", pctx.GetNamespace(), err)
 		}
-	} else {
-		pvcs, err = pctx.ListPvcsByPod(opts.podname)
+	default:
+		pvcs, err = pctx.ListPvcsByPod(opts.podname, listOpt)
 		if err != nil {
 			klog.Errorf("list pvcs of pod %v/%v failed, err %v", pctx.GetNamespace(), opts.podname, err)
 		}
 	}
 
-	plugin.Format(os.Stdout, pvcs)
+	// the status of pvcs is needed by the table and --unhealthy
+	var statuses []*plugin.PvcStatus
+	pvcs, _ = opts.filter.Filter(pvcs, nil)
+	if opts.printer == nil || opts.filter.Unhealthy {
+		statuses, err = pctx.GetPvcsDetail(pvcs)
+		if err != nil {
+			klog.Errorf("get detail of pvcs failed, err %v", err)
+		}
+		pvcs, statuses = opts.filter.Filter(pvcs, statuses)
+	}
+
+	if opts.printer != nil {
+		list, err := plugin.NewPvcList(pvcs)
+		if err != nil {
+			return err
+		}
+		return opts.printer.PrintObj(list, os.Stdout)
+	}
+
+	plugin.Format(os.Stdout, pvcs, statuses, plugin.FormatOption{
+		Wide:          *opts.printFlags.OutputFormat == plugin.OutputWide,
+		WithNamespace: opts.allNamespaces,
+	})
+	if claims != nil {
+		plugin.FormatWorkloadClaims(os.Stdout, opts.owner, claims)
+	}
 
 	return nil
 }
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fatsheep9146/kubectl-pvc/pkg/plugin"
+)
+
+var (
+	migrateExample = `
+	# show the steps to migrate the pvc to another storageclass without doing anything
+	kubectl pvc migrate <pvc> -n <namespace> --to-storage-class <storageclass> --dry-run
+
+	# copy the data of pvc into a new pvc <pvc>-migrated of another storageclass
+	kubectl pvc migrate <pvc> -n <namespace> --to-storage-class <storageclass>
+
+	# copy the data by rsync, and bind the new volume to the original pvc name, so that workloads need no change
+	kubectl pvc migrate <pvc> -n <namespace> --to-storage-class <storageclass> --method rsync --image <image-with-rsync> --swap
+
+	# the migration is resumed from the interrupted step by running the same command again
+`
+)
+
+type MigrateOption struct {
+	opt    plugin.MigrateOption
+	dryRun bool
+	pctx   *plugin.PvcContext
+}
+
+func NewMigrateOption() *MigrateOption {
+	return &MigrateOption{}
+}
+
+func NewMigrateCommand() *cobra.Command {
+	opts := NewMigrateOption()
+
+	cmd := &cobra.Command{
+		Use:     "migrate [namespace/]pvc --to-storage-class <storageclass>",
+		Short:   "copy the data of one pvc into a new pvc of another storageclass",
+		Example: migrateExample,
+		// errors of a long migration should not be buried in usage
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(pctx); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			if err := opts.Run(args); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.opt.StorageClass, "to-storage-class", "", "the storageclass of new pvc")
+	cmd.Flags().StringVar(&opts.opt.Target, "target", "", "the name of new pvc, <pvc>-migrated by default")
+	cmd.Flags().StringVar(&opts.opt.Method, "method", plugin.MigrateMethodTar, "the way to copy data, one of: tar|rsync")
+	cmd.Flags().StringVar(&opts.opt.Image, "image", "", "the image of copy job, busybox by default, an image with rsync must be given for --method rsync")
+	cmd.Flags().BoolVar(&opts.opt.Swap, "swap", false, "bind the new volume to a pvc with the original name after copying, the old volume is retained")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "only print the steps of migration")
+	cmd.Flags().DurationVar(&opts.opt.Timeout, "timeout", 0, "give up waiting for each step after this duration, zero means waiting forever")
+	return cmd
+}
+
+func (opts *MigrateOption) Complete(pctx *plugin.PvcContext) error {
+	opts.pctx = pctx
+	return nil
+}
+
+func (opts *MigrateOption) Validate() error {
+	if opts.opt.StorageClass == "" {
+		return fmt.Errorf("user should input the storageclass to migrate to by --to-storage-class")
+	}
+	switch opts.opt.Method {
+	case plugin.MigrateMethodTar:
+	case plugin.MigrateMethodRsync:
+		if opts.opt.Image == "" {
+			return fmt.Errorf("user should input an image with rsync by --image for --method rsync")
+		}
+	default:
+		return fmt.Errorf("unsupported method %q, should be one of: tar|rsync", opts.opt.Method)
+	}
+	return nil
+}
+
+func (opts *MigrateOption) Run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("user should input one pvc to migrate")
+	}
+
+	namespace, name := parsePvcArg(args[0])
+	plan, err := opts.pctx.PlanMigrate(namespace, name, opts.opt)
+	if err != nil {
+		return err
+	}
+	if opts.dryRun {
+		plugin.FormatMigratePlan(os.Stdout, plan)
+		return nil
+	}
+
+	start := time.Now()
+	if err := opts.pctx.Migrate(plan, os.Stdout); err != nil {
+		return fmt.Errorf("%v\nrun the same command again to resume the migration", err)
+	}
+	fmt.Fprintf(os.Stdout, "migration of pvc %s to storageclass %s finished in %v\n", plan.Source, plan.StorageClass, time.Since(start).Round(time.Second))
+	return nil
+}
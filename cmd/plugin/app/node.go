@@ -0,0 +1,88 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fatsheep9146/kubectl-pvc/pkg/plugin"
+)
+
+var (
+	nodeExample = `
+	# check all volumes attached to or used on given node, and the pvcs and pods they belong to
+	kubectl pvc node <node>
+
+	# print the volumes of given node in json
+	kubectl pvc node <node> -o json
+`
+)
+
+type NodeOption struct {
+	output string
+	pctx   *plugin.PvcContext
+}
+
+func NewNodeOption() *NodeOption {
+	return &NodeOption{}
+}
+
+func NewNodeCommand() *cobra.Command {
+	opts := NewNodeOption()
+
+	cmd := &cobra.Command{
+		Use:     "node <node>",
+		Short:   "check all volumes attached to or used on one node",
+		Example: nodeExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(pctx); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			if err := opts.Run(args); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "output format, one of: json|yaml")
+	return cmd
+}
+
+func (opts *NodeOption) Complete(pctx *plugin.PvcContext) error {
+	opts.pctx = pctx
+	return nil
+}
+
+func (opts *NodeOption) Validate() error {
+	switch opts.output {
+	case "", plugin.OutputJSON, plugin.OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q, allowed formats are: json,yaml", opts.output)
+}
+
+func (opts *NodeOption) Run(args []string) (err error) {
+	if len(args) == 0 {
+		return fmt.Errorf("user should input one node to check")
+	}
+
+	nodeStatus, err := opts.pctx.GetNodeDetail(args[0])
+	if err != nil {
+		return err
+	}
+
+	if opts.output != "" {
+		return plugin.PrintNodeStatus(os.Stdout, nodeStatus, opts.output)
+	}
+
+	plugin.FormatNodeDetail(os.Stdout, nodeStatus)
+
+	return nil
+}
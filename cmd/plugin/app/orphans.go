@@ -0,0 +1,88 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fatsheep9146/kubectl-pvc/pkg/plugin"
+)
+
+var (
+	orphansExample = `
+	# find pvcs of given namespace not used by any pod or workload, and leaked pvs and volumeattachments
+	kubectl pvc orphans -n <namespace>
+
+	# find orphans of all namespaces
+	kubectl pvc orphans -A
+
+	# print the orphans in json
+	kubectl pvc orphans -A -o json
+`
+)
+
+type OrphansOption struct {
+	allNamespaces bool
+	output        string
+	pctx          *plugin.PvcContext
+}
+
+func NewOrphansOption() *OrphansOption {
+	return &OrphansOption{}
+}
+
+func NewOrphansCommand() *cobra.Command {
+	opts := NewOrphansOption()
+
+	cmd := &cobra.Command{
+		Use:     "orphans",
+		Short:   "find pvcs, pvs and volumeattachments which are not used by anyone",
+		Example: orphansExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(pctx); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			if err := opts.Run(); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.allNamespaces, "all-namespaces", "A", false, "find orphan pvcs of all namespaces")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "output format, one of: json|yaml")
+	return cmd
+}
+
+func (opts *OrphansOption) Complete(pctx *plugin.PvcContext) error {
+	opts.pctx = pctx
+	return nil
+}
+
+func (opts *OrphansOption) Validate() error {
+	switch opts.output {
+	case "", plugin.OutputJSON, plugin.OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q, allowed formats are: json,yaml", opts.output)
+}
+
+func (opts *OrphansOption) Run() error {
+	report, err := opts.pctx.FindOrphans(opts.allNamespaces)
+	if err != nil {
+		return err
+	}
+
+	if opts.output != "" {
+		return plugin.PrintOrphans(os.Stdout, report, opts.output)
+	}
+
+	plugin.FormatOrphans(os.Stdout, report)
+	return nil
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/fatsheep9146/kubectl-pvc/pkg/plugin"
+)
+
+var (
+	resizeExample = `
+	# expand the pvc to 50Gi
+	kubectl pvc resize <pvc> -n <namespace> --to 50Gi
+
+	# expand the pvc and wait until the file system is expanded
+	kubectl pvc resize <pvc> -n <namespace> --to 50Gi --wait --timeout 10m
+`
+)
+
+type ResizeOption struct {
+	to      string
+	size    resource.Quantity
+	wait    bool
+	timeout time.Duration
+	pctx    *plugin.PvcContext
+}
+
+func NewResizeOption() *ResizeOption {
+	return &ResizeOption{}
+}
+
+func NewResizeCommand() *cobra.Command {
+	opts := NewResizeOption()
+
+	cmd := &cobra.Command{
+		Use:     "resize [namespace/]pvc --to <size>",
+		Short:   "expand one pvc and follow the expansion",
+		Example: resizeExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(pctx); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			if err := opts.Run(args); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.to, "to", "", "the new size of pvc, e.g. 50Gi")
+	cmd.Flags().BoolVar(&opts.wait, "wait", false, "wait until the capacity of pvc reaches the new size")
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", 5*time.Minute, "give up waiting after this duration, zero means waiting forever, only works with --wait")
+	return cmd
+}
+
+func (opts *ResizeOption) Complete(pctx *plugin.PvcContext) error {
+	opts.pctx = pctx
+	return nil
+}
+
+func (opts *ResizeOption) Validate() (err error) {
+	if opts.to == "" {
+		return fmt.Errorf("user should input the new size by --to")
+	}
+	opts.size, err = resource.ParseQuantity(opts.to)
+	if err != nil {
+		return fmt.Errorf("invalid size %q, err: %v", opts.to, err)
+	}
+	return nil
+}
+
+func (opts *ResizeOption) Run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("user should input one pvc to resize")
+	}
+
+	namespace, name := parsePvcArg(args[0])
+	plan, err := opts.pctx.PlanResize(namespace, name, opts.size)
+	if err != nil {
+		return err
+	}
+	if err := opts.pctx.ResizePvc(plan); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "persistentvolumeclaim/%s resized from %s to %s\n", plan.Name, plan.From.String(), plan.To.String())
+	restartHint := ""
+	if len(plan.Pods) > 0 {
+		restartHint = fmt.Sprintf("restart pods [%s] if the volume only supports offline expansion", strings.Join(plan.Pods, ","))
+	}
+
+	if !opts.wait {
+		if restartHint != "" {
+			fmt.Fprintf(os.Stdout, "the file system is expanded when the volume is mounted next time, %s\n", restartHint)
+		}
+		return nil
+	}
+
+	err = opts.pctx.WaitPvcResized(plan.Namespace, plan.Name, plan.To, opts.timeout, func(conditions []corev1.PersistentVolumeClaimCondition) {
+		now := time.Now().Format("15:04:05")
+		if len(conditions) == 0 {
+			fmt.Fprintf(os.Stdout, "%s   no resize condition\n", now)
+		}
+		for _, c := range conditions {
+			fmt.Fprintf(os.Stdout, "%s   %s   %s\n", now, c.Type, c.Message)
+			if c.Type == corev1.PersistentVolumeClaimFileSystemResizePending && restartHint != "" {
+				fmt.Fprintf(os.Stdout, "%s   %s\n", now, restartHint)
+			}
+		}
+	})
+	if err == plugin.ErrTimeout {
+		return fmt.Errorf("capacity of pvc %s does not reach %s within %v, run `kubectl pvc inspect %s/%s` for details",
+			plan.Name, plan.To.String(), opts.timeout, plan.Namespace, plan.Name)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "persistentvolumeclaim/%s expanded to %s\n", plan.Name, plan.To.String())
+	return nil
+}
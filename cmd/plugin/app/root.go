@@ -1,8 +1,11 @@
 package app
 
 import (
+	"flag"
+
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/klog"
 
 	"github.com/fatsheep9146/kubectl-pvc/pkg/plugin"
 )
@@ -11,13 +14,12 @@ var pctx *plugin.PvcContext = nil
 
 func NewPvcCommand(streams genericclioptions.IOStreams) *cobra.Command {
 	pctx = plugin.NewPvcContext(streams)
-	var ns string
 
 	cmd := &cobra.Command{
 		Use:   "pvc",
 		Short: "kubectl pvc: check info about pvc in faster way",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			err := pctx.Complete(ns)
+			err := pctx.Complete()
 			if err != nil {
 				return err
 			}
@@ -25,9 +27,22 @@ func NewPvcCommand(streams genericclioptions.IOStreams) *cobra.Command {
 		},
 	}
 
-	cmd.PersistentFlags().StringVarP(&ns, "namespace", "n", "default", "the namespace you want to check")
+	pctx.AddFlags(cmd.PersistentFlags())
+	// only the log level of klog is exposed, other klog flags are of no use for users
+	klogFlags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(klogFlags)
+	cmd.PersistentFlags().AddGoFlag(klogFlags.Lookup("v"))
 	cmd.AddCommand(NewLsCommand())
 	cmd.AddCommand(NewInspectCommand())
+	cmd.AddCommand(NewNodeCommand())
+	cmd.AddCommand(NewOrphansCommand())
+	cmd.AddCommand(NewWaitCommand())
+	cmd.AddCommand(NewDfCommand())
+	cmd.AddCommand(NewResizeCommand())
+	cmd.AddCommand(NewSnapshotCommand())
+	cmd.AddCommand(NewCloneCommand())
+	cmd.AddCommand(NewMigrateCommand())
+	cmd.AddCommand(NewBrowseCommand())
 
 	return cmd
 }
@@ -0,0 +1,230 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fatsheep9146/kubectl-pvc/pkg/plugin"
+)
+
+var (
+	snapshotExample = `
+	# snapshot the pvc with given VolumeSnapshotClass before a risky upgrade
+	kubectl pvc snapshot create <pvc> -n <namespace> --class <snapshotclass>
+
+	# list all snapshots of the pvc
+	kubectl pvc snapshot ls <pvc> -n <namespace>
+
+	# list snapshots of all namespaces
+	kubectl pvc snapshot ls -A
+
+	# restore the snapshot into a new pvc with the same size, storageclass and access modes as the source pvc
+	kubectl pvc snapshot restore <snapshot> <new-pvc> -n <namespace>
+`
+)
+
+func NewSnapshotCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "snapshot",
+		Short:   "create, list and restore VolumeSnapshots of pvcs",
+		Example: snapshotExample,
+	}
+
+	cmd.AddCommand(NewSnapshotCreateCommand())
+	cmd.AddCommand(NewSnapshotLsCommand())
+	cmd.AddCommand(NewSnapshotRestoreCommand())
+	return cmd
+}
+
+type SnapshotCreateOption struct {
+	opt  plugin.CreateSnapshotOption
+	pctx *plugin.PvcContext
+}
+
+func NewSnapshotCreateOption() *SnapshotCreateOption {
+	return &SnapshotCreateOption{}
+}
+
+func NewSnapshotCreateCommand() *cobra.Command {
+	opts := NewSnapshotCreateOption()
+
+	cmd := &cobra.Command{
+		Use:   "create [namespace/]pvc",
+		Short: "create a VolumeSnapshot of one pvc",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(pctx); err != nil {
+				return err
+			}
+
+			if err := opts.Run(args); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.opt.Name, "name", "", "the name of snapshot, <pvc>-<timestamp> by default")
+	cmd.Flags().StringVar(&opts.opt.SnapshotClass, "class", "", "the VolumeSnapshotClass to use, the default class of the csi driver is used if empty")
+	return cmd
+}
+
+func (opts *SnapshotCreateOption) Complete(pctx *plugin.PvcContext) error {
+	opts.pctx = pctx
+	return nil
+}
+
+func (opts *SnapshotCreateOption) Run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("user should input one pvc to snapshot")
+	}
+
+	namespace, name := parsePvcArg(args[0])
+	snapshot, err := opts.pctx.CreateSnapshot(namespace, name, opts.opt)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "volumesnapshot/%s created, run `kubectl pvc snapshot ls %s -n %s` to check whether it is ready to use\n",
+		snapshot.Name, name, snapshot.Namespace)
+	return nil
+}
+
+type SnapshotLsOption struct {
+	allNamespaces bool
+	output        string
+	pctx          *plugin.PvcContext
+}
+
+func NewSnapshotLsOption() *SnapshotLsOption {
+	return &SnapshotLsOption{}
+}
+
+func NewSnapshotLsCommand() *cobra.Command {
+	opts := NewSnapshotLsOption()
+
+	cmd := &cobra.Command{
+		Use:   "ls [pvc]",
+		Short: "list VolumeSnapshots of one pvc or the whole namespace",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(pctx); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(args); err != nil {
+				return err
+			}
+
+			if err := opts.Run(args); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.allNamespaces, "all-namespaces", "A", false, "list snapshots of all namespaces")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "", "output format, one of: json|yaml")
+	return cmd
+}
+
+func (opts *SnapshotLsOption) Complete(pctx *plugin.PvcContext) error {
+	opts.pctx = pctx
+	return nil
+}
+
+func (opts *SnapshotLsOption) Validate(args []string) error {
+	if opts.allNamespaces && len(args) > 0 {
+		return fmt.Errorf("pvc can not be given together with --all-namespaces")
+	}
+	switch opts.output {
+	case "", plugin.OutputJSON, plugin.OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q, allowed formats are: json,yaml", opts.output)
+}
+
+func (opts *SnapshotLsOption) Run(args []string) error {
+	pvcname := ""
+	if len(args) > 0 {
+		pvcname = args[0]
+	}
+
+	snapshots, err := opts.pctx.ListSnapshots(pvcname, opts.allNamespaces)
+	if err != nil {
+		return err
+	}
+
+	if opts.output != "" {
+		return plugin.PrintSnapshots(os.Stdout, snapshots, opts.output)
+	}
+
+	plugin.FormatSnapshots(os.Stdout, snapshots, opts.allNamespaces)
+	return nil
+}
+
+type SnapshotRestoreOption struct {
+	overrides plugin.PvcOverrides
+	pctx      *plugin.PvcContext
+}
+
+func NewSnapshotRestoreOption() *SnapshotRestoreOption {
+	return &SnapshotRestoreOption{}
+}
+
+func NewSnapshotRestoreCommand() *cobra.Command {
+	opts := NewSnapshotRestoreOption()
+
+	cmd := &cobra.Command{
+		Use:   "restore [namespace/]snapshot <new-pvc>",
+		Short: "restore a VolumeSnapshot into a new pvc",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(pctx); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			if err := opts.Run(args); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	addPvcOverridesFlags(cmd, &opts.overrides)
+	return cmd
+}
+
+// addPvcOverridesFlags adds the flags to override the spec copied from the source pvc
+func addPvcOverridesFlags(cmd *cobra.Command, o *plugin.PvcOverrides) {
+	cmd.Flags().StringVar(&o.StorageClass, "storage-class", "", "the storageclass of new pvc, the same as the source pvc by default")
+	cmd.Flags().StringSliceVar(&o.AccessModes, "access-mode", nil, "the access modes of new pvc, e.g. RWO, the same as the source pvc by default")
+	cmd.Flags().StringVar(&o.VolumeMode, "volume-mode", "", "the volume mode of new pvc, Filesystem or Block, the same as the source pvc by default")
+	cmd.Flags().StringVar(&o.Size, "size", "", "the size of new pvc, the same as the source pvc by default, it can not be smaller than the source")
+}
+
+func (opts *SnapshotRestoreOption) Complete(pctx *plugin.PvcContext) error {
+	opts.pctx = pctx
+	return nil
+}
+
+func (opts *SnapshotRestoreOption) Validate() error {
+	return opts.overrides.Validate()
+}
+
+func (opts *SnapshotRestoreOption) Run(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("user should input one snapshot to restore and the name of new pvc")
+	}
+
+	namespace, name := parsePvcArg(args[0])
+	pvc, err := opts.pctx.RestoreSnapshot(namespace, name, args[1], opts.overrides)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "persistentvolumeclaim/%s created from volumesnapshot/%s, run `kubectl pvc inspect %s/%s` to follow it\n",
+		pvc.Name, name, pvc.Namespace, pvc.Name)
+	return nil
+}
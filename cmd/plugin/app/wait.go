@@ -0,0 +1,128 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fatsheep9146/kubectl-pvc/pkg/plugin"
+)
+
+var (
+	waitExample = `
+	# wait until the pvc is bound to a pv
+	kubectl pvc wait <pvc> -n <namespace> --for=bound
+
+	# wait until several pvcs are mounted by their pods, and give up after 5 minutes
+	kubectl pvc wait <pvc1> <pvc2> -n <namespace> --for=mounted --timeout 5m
+
+	# wait until all pvcs with given labels are attached
+	kubectl pvc wait -n <namespace> -l app=foo --for=phase=Attach
+`
+)
+
+// exit codes of wait, other errors exit with 1
+const (
+	ExitCodeTimeout     = 2
+	ExitCodeHardFailure = 3
+)
+
+// ExitError makes the plugin exit with the code instead of 1
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+type WaitOption struct {
+	condition     string
+	labelSelector string
+	timeout       time.Duration
+	phase         plugin.PvcPhaseName
+	pctx          *plugin.PvcContext
+}
+
+func NewWaitOption() *WaitOption {
+	return &WaitOption{}
+}
+
+func NewWaitCommand() *cobra.Command {
+	opts := NewWaitOption()
+
+	cmd := &cobra.Command{
+		Use:     "wait <pvc>... | -l <selector>",
+		Short:   "wait until pvcs reach one phase of their lifecycle",
+		Example: waitExample,
+		// exit code of timeout or hard failure should not be mixed with usage
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(pctx); err != nil {
+				return err
+			}
+
+			if err := opts.Validate(args); err != nil {
+				return err
+			}
+
+			if err := opts.Run(args); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.condition, "for", "bound", "the condition to wait for, one of: bound|attached|mounted|resized|phase=Provision|Bind|Attach|Mount|Resize")
+	cmd.Flags().StringVarP(&opts.labelSelector, "selector", "l", "", "label selector of pvcs to wait for, e.g. -l key1=value1,key2=value2")
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", 30*time.Second, "give up after this duration, zero means waiting forever")
+	return cmd
+}
+
+func (opts *WaitOption) Complete(pctx *plugin.PvcContext) error {
+	opts.pctx = pctx
+	return nil
+}
+
+func (opts *WaitOption) Validate(args []string) (err error) {
+	if len(args) == 0 && opts.labelSelector == "" {
+		return fmt.Errorf("user should input pvcs or a selector to wait for")
+	}
+	if len(args) > 0 && opts.labelSelector != "" {
+		return fmt.Errorf("pvcs can not be given together with --selector")
+	}
+	opts.phase, err = plugin.ParseWaitCondition(opts.condition)
+	return err
+}
+
+func (opts *WaitOption) Run(args []string) error {
+	pvcnames := args
+	if opts.labelSelector != "" {
+		pvcs, err := opts.pctx.ListPvcs(plugin.ListPvcsOption{LabelSelector: opts.labelSelector})
+		if err != nil {
+			return err
+		}
+		if len(pvcs) == 0 {
+			return fmt.Errorf("no pvcs found in namespace %s with selector %s", opts.pctx.GetNamespace(), opts.labelSelector)
+		}
+		for _, pvc := range pvcs {
+			pvcnames = append(pvcnames, pvc.Name)
+		}
+	}
+
+	err := opts.pctx.WaitPvcs("", pvcnames, opts.phase, opts.timeout, func(name string) {
+		fmt.Fprintf(os.Stdout, "persistentvolumeclaim/%s condition met\n", name)
+	})
+	switch err.(type) {
+	case nil:
+		return nil
+	case *plugin.WaitTimeoutError:
+		return &ExitError{Code: ExitCodeTimeout, Err: err}
+	case *plugin.PvcFailedError:
+		return &ExitError{Code: ExitCodeHardFailure, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+package plugin
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog"
+)
+
+// versions of storage.k8s.io serving CSIDriver, from the newest to the oldest
+var csiDriverVersions = []string{"v1", "v1beta1"}
+
+// isAttachRequired returns whether volumes of the csi driver are attached to node, it is true unless
+// the CSIDriver object of driver says attachRequired is false
+func (p *PvcContext) isAttachRequired(objs *clusterObjects, driver string) (bool, error) {
+	if required, ok := objs.attachRequired[driver]; ok {
+		return required, nil
+	}
+
+	required := true
+	for _, version := range csiDriverVersions {
+		gvr := schema.GroupVersionResource{Group: storagev1.GroupName, Version: version, Resource: "csidrivers"}
+		obj, err := p.dyncli.Resource(gvr).Get(driver, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			// either the version is not served or the driver has no CSIDriver object
+			continue
+		}
+		if apierrors.IsForbidden(err) {
+			klog.V(2).Infof("get info about csidriver [%s] is forbidden, assume volumes are attached", driver)
+			break
+		}
+		if err != nil {
+			return false, fmt.Errorf("get info about csidriver [%s] failed, err: %v", driver, err)
+		}
+		required = csiDriverAttachRequired(obj)
+		break
+	}
+	objs.attachRequired[driver] = required
+	return required, nil
+}
+
+// csiDriverAttachRequired reads spec.attachRequired of CSIDriver, which defaults to true
+func csiDriverAttachRequired(driver *unstructured.Unstructured) bool {
+	required, found, err := unstructured.NestedBool(driver.Object, "spec", "attachRequired")
+	if err != nil || !found {
+		return true
+	}
+	return required
+}
+
+// getAttachedNodes returns the nodes this pv is attached to (or is being attached to).
+// For csi pv VolumeAttachment objects are the source of truth, the volumesAttached of Node
+// is only used on old clusters which do not serve storage.k8s.io/v1 VolumeAttachment.
+// In-tree pv are attached by the attach/detach controller without VolumeAttachment,
+// so only the volumesAttached of Node is checked.
+func (p *PvcContext) getAttachedNodes(objs *clusterObjects, pvStatus *PVStatus) ([]*Node, error) {
+	if pvStatus.Plugin != csiPluginName {
+		return p.getAttachedNodesByNodeStatus(objs, pvStatus.AttachedVolumeName)
+	}
+
+	nodes, served, err := p.getAttachedNodesByVolumeAttachment(objs, pvStatus.Name)
+	if err != nil {
+		return nil, err
+	}
+	if served {
+		return nodes, nil
+	}
+
+	klog.V(2).Infof("volumeattachments are not served by apiserver, fall back to volumesAttached of nodes")
+	return p.getAttachedNodesByNodeStatus(objs, pvStatus.AttachedVolumeName)
+}
+
+// getAttachedNodesByVolumeAttachment returns false if VolumeAttachment is not served by apiserver
+func (p *PvcContext) getAttachedNodesByVolumeAttachment(objs *clusterObjects, pvname string) ([]*Node, bool, error) {
+	vas, served, err := p.listVolumeAttachments(objs)
+	if err != nil || !served {
+		return nil, served, err
+	}
+
+	nodes := make([]*Node, 0)
+	for i := range vas {
+		if isVolumeAttachmentOfPv(pvname, &vas[i]) {
+			nodes = append(nodes, NewNodeFromVolumeAttachment(&vas[i]))
+		}
+	}
+	return nodes, true, nil
+}
+
+func (p *PvcContext) getAttachedNodesByNodeStatus(objs *clusterObjects, attachedVolumeName string) ([]*Node, error) {
+	nodeList, err := p.listNodes(objs)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*Node, 0)
+	for i := range nodeList {
+		if isPvAttachToNode(attachedVolumeName, &nodeList[i]) {
+			nodes = append(nodes, NewNode(&nodeList[i]))
+		}
+	}
+	return nodes, nil
+}
+
+func isVolumeAttachmentOfPv(pvname string, va *storagev1.VolumeAttachment) bool {
+	return va.Spec.Source.PersistentVolumeName != nil && *va.Spec.Source.PersistentVolumeName == pvname
+}
+
+func NewNodeFromVolumeAttachment(va *storagev1.VolumeAttachment) *Node {
+	n := &Node{
+		Name:                va.Spec.NodeName,
+		Attached:            va.Status.Attached,
+		VolumeAttachment:    va.Name,
+		Detaching:           va.DeletionTimestamp != nil,
+		volumeAttachmentUID: va.UID,
+	}
+	if va.Status.AttachError != nil {
+		n.AttachError = va.Status.AttachError.Message
+	}
+	if va.Status.DetachError != nil {
+		n.DetachError = va.Status.DetachError.Message
+	}
+	return n
+}
+
+const (
+	NodeStateReady    = "Ready"
+	NodeStateNotReady = "NotReady"
+	NodeStateUnknown  = "Unknown"
+	NodeStateDeleted  = "Deleted"
+)
+
+// accessModeReadWriteOncePod is not in the api of this version, it is also attached to a single node
+const accessModeReadWriteOncePod corev1.PersistentVolumeAccessMode = "ReadWriteOncePod"
+
+// staleNodes returns the nodes where the volume is still attached without any pod desiring it
+func staleNodes(nodes []*Node) []*Node {
+	stale := make([]*Node, 0)
+	for _, node := range nodes {
+		if node.Attached && !node.Desired {
+			stale = append(stale, node)
+		}
+	}
+	return stale
+}
+
+func (p *PvcContext) completeNodeState(objs *clusterObjects, nodes []*Node) error {
+	for _, node := range nodes {
+		n, err := p.getNode(objs, node.Name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				node.State = NodeStateDeleted
+				continue
+			}
+			return fmt.Errorf("get info about node [%s] failed, err: %v", node.Name, err)
+		}
+		node.State = getNodeState(n)
+	}
+	return nil
+}
+
+func getNodeState(n *corev1.Node) string {
+	for _, cond := range n.Status.Conditions {
+		if cond.Type != corev1.NodeReady {
+			continue
+		}
+		switch cond.Status {
+		case corev1.ConditionTrue:
+			return NodeStateReady
+		case corev1.ConditionFalse:
+			return NodeStateNotReady
+		}
+	}
+	return NodeStateUnknown
+}
+
+func isReadWriteOnce(pvc *corev1.PersistentVolumeClaim) bool {
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode == corev1.ReadWriteOnce || mode == accessModeReadWriteOncePod {
+			return true
+		}
+	}
+	return false
+}
+
+func formatNodesWithState(nodes []*Node) []string {
+	n := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		n = append(n, fmt.Sprintf("%s(%s)", node.Name, node.State))
+	}
+	return n
+}
+
+func formatStaleNodesWarnings(nodes []*Node) []string {
+	warnings := make([]string, 0)
+	for _, node := range nodes {
+		var msg string
+		if node.State == NodeStateDeleted {
+			msg = fmt.Sprintf("node %s is deleted, but volume is still attached to it", node.Name)
+		} else {
+			msg = fmt.Sprintf("volume is still attached to node %s(%s) without any pod using it", node.Name, node.State)
+		}
+		if node.Detaching {
+			msg = fmt.Sprintf("%s, detaching is in progress", msg)
+		}
+		warnings = append(warnings, msg)
+	}
+	return warnings
+}
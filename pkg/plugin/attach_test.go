@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCsiDriverAttachRequired(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     map[string]interface{}
+		required bool
+	}{
+		{"attach not required", map[string]interface{}{"attachRequired": false}, false},
+		{"attach required", map[string]interface{}{"attachRequired": true}, true},
+		{"default", map[string]interface{}{"podInfoOnMount": true}, true},
+		{"malformed", map[string]interface{}{"attachRequired": "false"}, true},
+	}
+	for _, test := range tests {
+		driver := &unstructured.Unstructured{Object: map[string]interface{}{"spec": test.spec}}
+		if required := csiDriverAttachRequired(driver); required != test.required {
+			t.Errorf("%s: expected attachRequired %v, got %v", test.name, test.required, required)
+		}
+	}
+}
+
+func TestDeducePhaseAttach(t *testing.T) {
+	tests := []struct {
+		name          string
+		attached      []*Node
+		desired       []string
+		readWriteOnce bool
+		status        PvcPhaseStatus
+		detail        string
+	}{
+		{
+			name:   "not desired by any pod",
+			status: PvcPhaseNotApplicable,
+			detail: "no pod using this pvc is scheduled to node",
+		},
+		{
+			name:     "attached to desired node",
+			attached: []*Node{{Name: "node-1", Attached: true}},
+			desired:  []string{"node-1"},
+			status:   PvcPhaseSuccess,
+		},
+		{
+			name:     "attaching",
+			attached: []*Node{{Name: "node-1"}},
+			desired:  []string{"node-1"},
+			status:   PvcPhaseFail,
+			detail:   "nodes: [node-1] are still not attached as desired",
+		},
+		{
+			name:     "attach error",
+			attached: []*Node{{Name: "node-1", AttachError: "rpc error: timeout"}},
+			desired:  []string{"node-1"},
+			status:   PvcPhaseFail,
+			detail:   "node node-1 attach error: rpc error: timeout",
+		},
+		{
+			name:     "attached to some of desired nodes",
+			attached: []*Node{{Name: "node-1", Attached: true}},
+			desired:  []string{"node-1", "node-2"},
+			status:   PvcPhasePartlyFail,
+			detail:   "nodes: [node-2] are still not attached as desired",
+		},
+		{
+			name:          "readwriteonce volume is still attached to old node",
+			attached:      []*Node{{Name: "node-1", Attached: true, State: NodeStateNotReady}},
+			desired:       []string{"node-2"},
+			readWriteOnce: true,
+			status:        PvcPhaseFail,
+			detail:        "still attached to nodes: [node-1(NotReady)]",
+		},
+	}
+	for _, test := range tests {
+		desired := make(map[string]struct{})
+		for _, node := range test.desired {
+			desired[node] = struct{}{}
+		}
+		for _, node := range test.attached {
+			_, node.Desired = desired[node.Name]
+		}
+		phase := deducePhaseAttach(test.attached, desired, test.readWriteOnce)
+		if phase.Status != test.status {
+			t.Errorf("%s: expected status %q, got %q: %s", test.name, test.status, phase.Status, phase.Detail)
+			continue
+		}
+		if !strings.Contains(phase.Detail, test.detail) {
+			t.Errorf("%s: expected detail containing %q, got %q", test.name, test.detail, phase.Detail)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	annBindCompleted     = "pv.kubernetes.io/bind-completed"
+	annBoundByController = "pv.kubernetes.io/bound-by-controller"
+)
+
+// deducePhaseBind validates both sides of the binding between pvc and pv,
+// pv is nil if it is not found
+func deducePhaseBind(pvc *corev1.PersistentVolumeClaim, pv *corev1.PersistentVolume) *PvcPhase {
+	phase := &PvcPhase{
+		Name: PvcBind,
+	}
+
+	if pv == nil {
+		phase.Status = PvcPhaseFail
+		phase.Detail = fmt.Sprintf("pv %s is not found", pvc.Spec.VolumeName)
+		return phase
+	}
+
+	fails := make([]string, 0)
+	waits := make([]string, 0)
+
+	if pvc.Status.Phase == corev1.ClaimLost {
+		fails = append(fails, fmt.Sprintf("pvc is lost, pv %s is deleted or bound to other pvc", pv.Name))
+		phase.hard = true
+	}
+
+	claimRef := pv.Spec.ClaimRef
+	switch {
+	case claimRef == nil:
+		// pv controller sets claimRef of pv before it sets volumeName of pvc,
+		// only pvc pre-bound by user can get here
+		waits = append(waits, "pv has no claimRef, waiting for pv controller to bind it to pvc")
+	case claimRef.Namespace != pvc.Namespace || claimRef.Name != pvc.Name:
+		fails = append(fails, fmt.Sprintf("pv is bound to other pvc %s/%s", claimRef.Namespace, claimRef.Name))
+		phase.hard = true
+	case claimRef.UID == "":
+		waits = append(waits, "pv is pre-bound to pvc, waiting for pv controller to complete binding")
+	case claimRef.UID != pvc.UID:
+		fails = append(fails, fmt.Sprintf("claimRef uid %s of pv does not match uid %s of pvc, pvc may be recreated", claimRef.UID, pvc.UID))
+		phase.hard = true
+	}
+
+	switch pv.Status.Phase {
+	case corev1.VolumeReleased:
+		fails = append(fails, "pv is released, its claim was deleted")
+	case corev1.VolumeFailed:
+		fails = append(fails, fmt.Sprintf("pv is failed: %s", pv.Status.Message))
+		phase.hard = true
+	case corev1.VolumePending, corev1.VolumeAvailable:
+		waits = append(waits, fmt.Sprintf("pv is still %s", pv.Status.Phase))
+	}
+
+	// pv controller only checks whether pv matches pvc before they are bound,
+	// the capacity of pvc may be larger than pv when it is being expanded
+	if pvc.Status.Phase != corev1.ClaimBound {
+		fails = append(fails, checkPvMatchesPvc(pvc, pv)...)
+		if pvc.Status.Phase == corev1.ClaimPending {
+			waits = append(waits, "pvc is still Pending")
+		}
+	}
+
+	switch {
+	case len(fails) > 0:
+		phase.Status = PvcPhaseFail
+	case len(waits) > 0:
+		phase.Status = PvcPhaseOndoing
+	default:
+		phase.Status = PvcPhaseSuccess
+	}
+	msgs := append(fails, waits...)
+	msgs = append(msgs, formatBindAnnotations(pvc, pv))
+	phase.Detail = strings.Join(msgs, "; ")
+
+	return phase
+}
+
+// deducePhasePreBind checks the pv pre-bound to the pvc by claimRef while volumeName of pvc is not set yet
+func deducePhasePreBind(pvc *corev1.PersistentVolumeClaim, pv *corev1.PersistentVolume) *PvcPhase {
+	phase := &PvcPhase{
+		Name: PvcBind,
+	}
+
+	mismatches := checkPvMatchesPvc(pvc, pv)
+	if len(mismatches) > 0 {
+		phase.Status = PvcPhaseFail
+		phase.Detail = fmt.Sprintf("pv %s is pre-bound to pvc but does not match it: %s", pv.Name, strings.Join(mismatches, "; "))
+		return phase
+	}
+
+	phase.Status = PvcPhaseOndoing
+	phase.Detail = fmt.Sprintf("pv %s is pre-bound to pvc, waiting for pv controller to complete binding", pv.Name)
+	return phase
+}
+
+// checkPvMatchesPvc returns the reasons why pv controller refuses to bind the pv to pvc
+func checkPvMatchesPvc(pvc *corev1.PersistentVolumeClaim, pv *corev1.PersistentVolume) []string {
+	mismatches := make([]string, 0)
+
+	request := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity := pv.Spec.Capacity[corev1.ResourceStorage]
+	if capacity.Cmp(request) < 0 {
+		mismatches = append(mismatches, fmt.Sprintf("pv capacity %s is less than pvc request %s", capacity.String(), request.String()))
+	}
+
+	for _, mode := range pvc.Spec.AccessModes {
+		if !containsAccessMode(pv.Spec.AccessModes, mode) {
+			mismatches = append(mismatches, fmt.Sprintf("pv access modes %v do not contain %s", pv.Spec.AccessModes, mode))
+		}
+	}
+
+	pvcMode := getVolumeMode(pvc.Spec.VolumeMode)
+	pvMode := getVolumeMode(pv.Spec.VolumeMode)
+	if pvcMode != pvMode {
+		mismatches = append(mismatches, fmt.Sprintf("pv volumeMode %s does not match pvc volumeMode %s", pvMode, pvcMode))
+	}
+
+	pvcClass, _ := getPvcStorageClassName(pvc)
+	pvClass := getPvStorageClassName(pv)
+	if pvcClass != pvClass {
+		mismatches = append(mismatches, fmt.Sprintf("pv storageclass %q does not match pvc storageclass %q", pvClass, pvcClass))
+	}
+
+	return mismatches
+}
+
+func formatBindAnnotations(pvc *corev1.PersistentVolumeClaim, pv *corev1.PersistentVolume) string {
+	return fmt.Sprintf("bind-completed: %t, pvc bound-by-controller: %t, pv bound-by-controller: %t",
+		metav1.HasAnnotation(pvc.ObjectMeta, annBindCompleted),
+		metav1.HasAnnotation(pvc.ObjectMeta, annBoundByController),
+		metav1.HasAnnotation(pv.ObjectMeta, annBoundByController))
+}
+
+func containsAccessMode(modes []corev1.PersistentVolumeAccessMode, mode corev1.PersistentVolumeAccessMode) bool {
+	for _, m := range modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func getVolumeMode(mode *corev1.PersistentVolumeMode) corev1.PersistentVolumeMode {
+	if mode == nil {
+		return corev1.PersistentVolumeFilesystem
+	}
+	return *mode
+}
+
+func getPvStorageClassName(pv *corev1.PersistentVolume) string {
+	if class, ok := pv.Annotations[annBetaStorageClass]; ok {
+		return class
+	}
+	return pv.Spec.StorageClassName
+}
+
+// findPreBoundPv returns the pv whose claimRef points at the pvc while the pvc is not bound yet,
+// nil if there is none
+func (p *PvcContext) findPreBoundPv(objs *clusterObjects, pvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolume, error) {
+	pvs, err := p.listPvs(objs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pvs {
+		ref := pvs[i].Spec.ClaimRef
+		if ref == nil || ref.Namespace != pvc.Namespace || ref.Name != pvc.Name {
+			continue
+		}
+		if ref.UID == "" || ref.UID == pvc.UID {
+			return &pvs[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,231 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+	"k8s.io/klog"
+)
+
+const (
+	// label of browse helper pods, the value is the name of pvc
+	labelBrowse = "kubectl-pvc/browse"
+
+	// the container of browse helper pod which the commands are run in
+	browseContainer = "browse"
+
+	// the path where the pvc is mounted in browse helper pod
+	BrowseMountPath = "/data"
+
+	DefaultBrowseImage = "busybox"
+)
+
+type BrowseOption struct {
+	Image string
+	// mount the pvc read-write instead of read-only
+	ReadWrite bool
+	// the helper pod is killed after this duration in case it is not deleted by the plugin
+	MaxLifetime time.Duration
+	// timeout of waiting for the helper pod to run, zero means waiting forever
+	Timeout time.Duration
+}
+
+// StartBrowseHelper creates a helper pod which mounts the pvc and waits until it is running. The helper pod
+// is pinned to the node where a ReadWriteOnce volume is attached, so that it can mount the volume too.
+func (p *PvcContext) StartBrowseHelper(namespace, pvcname string, opt BrowseOption) (*corev1.Pod, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+	if namespace == "" {
+		namespace = p.namespace
+	}
+	if opt.Image == "" {
+		opt.Image = DefaultBrowseImage
+	}
+
+	cli := p.k8scli
+	pvc, err := cli.CoreV1().PersistentVolumeClaims(namespace).Get(pvcname, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", namespace, pvcname, err)
+	}
+	if getVolumeMode(pvc.Spec.VolumeMode) == corev1.PersistentVolumeBlock {
+		return nil, fmt.Errorf("pvc [%s/%s] is a block volume without file system, it can not be browsed", namespace, pvcname)
+	}
+	// the helper pod would be pending, or provision the volume of a WaitForFirstConsumer pvc on a random node
+	if pvc.Status.Phase != corev1.ClaimBound {
+		return nil, fmt.Errorf("pvc [%s/%s] is %s, only bound pvc can be browsed", namespace, pvcname, pvc.Status.Phase)
+	}
+
+	node, err := p.browseNode(pvc)
+	if err != nil {
+		return nil, err
+	}
+
+	lifetime := int64(opt.MaxLifetime.Seconds())
+	gracePeriod := int64(0)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: pvcname + "-browse-",
+			Namespace:    namespace,
+			Labels:       map[string]string{labelBrowse: pvcname},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:                      node,
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds:         &lifetime,
+			TerminationGracePeriodSeconds: &gracePeriod,
+			Containers: []corev1.Container{{
+				Name:       browseContainer,
+				Image:      opt.Image,
+				Command:    []string{"sleep", fmt.Sprintf("%d", lifetime)},
+				WorkingDir: BrowseMountPath,
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "data",
+					MountPath: BrowseMountPath,
+					ReadOnly:  !opt.ReadWrite,
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: pvcname,
+					ReadOnly:  !opt.ReadWrite,
+				}},
+			}},
+		},
+	}
+	pod, err = cli.CoreV1().Pods(namespace).Create(pod)
+	if err != nil {
+		return nil, fmt.Errorf("create browse helper pod for pvc [%s/%s] failed, err: %v", namespace, pvcname, err)
+	}
+
+	if err := p.waitBrowseHelper(pod, opt.Timeout); err != nil {
+		if err := p.DeleteBrowseHelper(pod); err != nil {
+			klog.Errorf("%v", err)
+		}
+		return nil, err
+	}
+	return pod, nil
+}
+
+// browseNode returns the node the helper pod must run on, it is empty if the pod can be scheduled anywhere
+func (p *PvcContext) browseNode(pvc *corev1.PersistentVolumeClaim) (string, error) {
+	shared := false
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode == corev1.ReadWriteMany || mode == corev1.ReadOnlyMany {
+			shared = true
+		}
+	}
+	if shared {
+		return "", nil
+	}
+
+	pods, err := p.podsUsingPvc(pvc.Namespace, pvc.Name)
+	if err != nil {
+		return "", err
+	}
+	running := make([]string, 0)
+	node := ""
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning && pod.Spec.NodeName != "" {
+			running = append(running, pod.Name)
+			node = pod.Spec.NodeName
+		}
+	}
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode == accessModeReadWriteOncePod && len(running) > 0 {
+			return "", fmt.Errorf("pvc [%s/%s] is ReadWriteOncePod and used by pod %v, it can not be mounted by another pod",
+				pvc.Namespace, pvc.Name, running)
+		}
+	}
+	if node != "" {
+		return node, nil
+	}
+
+	// the volume may be still attached after its pods are gone
+	nodes, _, err := p.getAttachedNodesByVolumeAttachment(newClusterObjects(false), pvc.Spec.VolumeName)
+	if err != nil {
+		return "", err
+	}
+	for _, n := range nodes {
+		if n.Attached && !n.Detaching {
+			return n.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// waitBrowseHelper waits until the helper pod is running
+func (p *PvcContext) waitBrowseHelper(pod *corev1.Pod, timeout time.Duration) error {
+	var lastErr error
+	err := p.watchUntil(pod.Namespace, timeout, func() bool {
+		current, err := p.k8scli.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+		if err != nil {
+			lastErr = fmt.Errorf("get info about pod [%s/%s] failed, err: %v", pod.Namespace, pod.Name, err)
+			return true
+		}
+		switch current.Status.Phase {
+		case corev1.PodRunning:
+			return true
+		case corev1.PodSucceeded, corev1.PodFailed:
+			lastErr = fmt.Errorf("browse helper pod [%s/%s] is %s: %s", pod.Namespace, pod.Name, current.Status.Phase, current.Status.Message)
+			return true
+		}
+		return false
+	})
+	if err == ErrTimeout {
+		return fmt.Errorf("browse helper pod [%s/%s] is not running in %v, run `kubectl pvc inspect %s/%s` to check whether the volume can be mounted",
+			pod.Namespace, pod.Name, timeout, pod.Namespace, pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	}
+	if err != nil {
+		return err
+	}
+	return lastErr
+}
+
+// ExecBrowseHelper runs the command in the helper pod through the exec subresource of pod, it connects to
+// apiserver with the same credentials as the plugin. The error is a utilexec.ExitError if the command exits
+// with non-zero code.
+func (p *PvcContext) ExecBrowseHelper(pod *corev1.Pod, command []string, streams remotecommand.StreamOptions) error {
+	req := p.k8scli.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: browseContainer,
+			Command:   command,
+			Stdin:     streams.Stdin != nil,
+			Stdout:    streams.Stdout != nil,
+			Stderr:    streams.Stderr != nil,
+			TTY:       streams.Tty,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(p.config, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("exec into pod [%s/%s] failed, err: %v", pod.Namespace, pod.Name, err)
+	}
+	err = executor.Stream(streams)
+	if _, ok := err.(utilexec.ExitError); ok {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("exec into pod [%s/%s] failed, err: %v", pod.Namespace, pod.Name, err)
+	}
+	return nil
+}
+
+// DeleteBrowseHelper deletes the helper pod immediately
+func (p *PvcContext) DeleteBrowseHelper(pod *corev1.Pod) error {
+	gracePeriod := int64(0)
+	err := p.k8scli.CoreV1().Pods(pod.Namespace).Delete(pod.Name, &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+	if err != nil {
+		return fmt.Errorf("delete browse helper pod [%s/%s] failed, err: %v", pod.Namespace, pod.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClonePvc creates a new pvc in the same namespace with the source pvc as its dataSource, the spec is copied
+// from the source pvc. Cloning is only supported by csi drivers, and only inside the same driver and volume mode.
+func (p *PvcContext) ClonePvc(namespace, srcname, dstname string, o PvcOverrides) (*corev1.PersistentVolumeClaim, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+	if namespace == "" {
+		namespace = p.namespace
+	}
+
+	cli := p.k8scli
+	src, err := cli.CoreV1().PersistentVolumeClaims(namespace).Get(srcname, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", namespace, srcname, err)
+	}
+	if src.Status.Phase != corev1.ClaimBound {
+		return nil, fmt.Errorf("pvc [%s/%s] is %s, only bound pvc can be cloned", namespace, srcname, src.Status.Phase)
+	}
+	pv, err := cli.CoreV1().PersistentVolumes().Get(src.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about pv [%s] failed, err: %v", src.Spec.VolumeName, err)
+	}
+	if pv.Spec.CSI == nil {
+		return nil, fmt.Errorf("pv [%s] is not a csi volume, only csi volumes can be cloned", pv.Name)
+	}
+
+	// the size of clone can not be smaller than the source
+	minSize := src.Spec.Resources.Requests[corev1.ResourceStorage]
+	if capacity, ok := src.Status.Capacity[corev1.ResourceStorage]; ok && capacity.Cmp(minSize) > 0 {
+		minSize = capacity
+	}
+	dst, err := newPvcFromSource(namespace, dstname, src, minSize, &corev1.TypedLocalObjectReference{
+		Kind: KindPersistentVolumeClaim,
+		Name: srcname,
+	}, o)
+	if err != nil {
+		return nil, err
+	}
+
+	if getVolumeMode(dst.Spec.VolumeMode) != getVolumeMode(src.Spec.VolumeMode) {
+		return nil, fmt.Errorf("volume mode of clone should be %s, the same as pvc [%s/%s]", getVolumeMode(src.Spec.VolumeMode), namespace, srcname)
+	}
+
+	class, _ := getPvcStorageClassName(dst)
+	if class == "" {
+		return nil, fmt.Errorf("clone of pvc [%s/%s] should have a storageclass to be provisioned by %s", namespace, srcname, pv.Spec.CSI.Driver)
+	}
+	sc, err := cli.StorageV1().StorageClasses().Get(class, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about storageclass [%s] failed, err: %v", class, err)
+	}
+	if sc.Provisioner != pv.Spec.CSI.Driver {
+		return nil, fmt.Errorf("storageclass [%s] is provisioned by %s, but pvc [%s/%s] can only be cloned by its driver %s",
+			class, sc.Provisioner, namespace, srcname, pv.Spec.CSI.Driver)
+	}
+
+	dst, err = cli.CoreV1().PersistentVolumeClaims(namespace).Create(dst)
+	if err != nil {
+		return nil, fmt.Errorf("create pvc [%s/%s] failed, err: %v", namespace, dstname, err)
+	}
+	return dst, nil
+}
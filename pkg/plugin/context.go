@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog"
@@ -15,6 +19,7 @@ import (
 type PvcContext struct {
 	flags     *genericclioptions.ConfigFlags
 	k8scli    *kubernetes.Clientset
+	dyncli    dynamic.Interface
 	config    *rest.Config
 	namespace string
 }
@@ -25,11 +30,22 @@ func NewPvcContext(streams genericclioptions.IOStreams) *PvcContext {
 	}
 }
 
-func (p *PvcContext) Complete(namespace string) (err error) {
-	p.namespace = namespace
+// AddFlags adds the standard kubectl flags about kubeconfig and connection, including --namespace
+func (p *PvcContext) AddFlags(flags *pflag.FlagSet) {
+	p.flags.AddFlags(flags)
+}
 
+func (p *PvcContext) Complete() (err error) {
 	configLoader := p.flags.ToRawKubeConfigLoader()
 
+	// the namespace is resolved the same way as kubectl, --namespace first
+	// and then the namespace of current context in kubeconfig
+	p.namespace, _, err = configLoader.Namespace()
+	if err != nil {
+		klog.Errorf("resolve namespace from kubeconfig failed, err: %v", err)
+		return err
+	}
+
 	p.config, err = configLoader.ClientConfig()
 	if err != nil {
 		klog.Errorf("initial rest.Config obj config failed, err: %v", err)
@@ -39,11 +55,26 @@ func (p *PvcContext) Complete(namespace string) (err error) {
 	p.k8scli, err = kubernetes.NewForConfig(p.config)
 	if err != nil {
 		klog.Errorf("initial kubernetes.clientset obj k8scli failed, err: %v", err)
+		return err
+	}
+
+	p.dyncli, err = dynamic.NewForConfig(p.config)
+	if err != nil {
+		klog.Errorf("initial dynamic.Interface obj dyncli failed, err: %v", err)
+		return err
 	}
 	return nil
 }
 
-func (p *PvcContext) ListPvcs() (pvcs []corev1.PersistentVolumeClaim, err error) {
+type ListPvcsOption struct {
+	// list pvcs of all namespaces instead of the namespace of context
+	AllNamespaces bool
+	LabelSelector string
+	FieldSelector string
+}
+
+// ListPvcs lists pvcs of the namespace of context, selectors are passed to kubernetes apiserver
+func (p *PvcContext) ListPvcs(opt ListPvcsOption) (pvcs []corev1.PersistentVolumeClaim, err error) {
 	pvcs = make([]corev1.PersistentVolumeClaim, 0)
 	if p.k8scli == nil {
 		return pvcs, fmt.Errorf("PvcContext.k8scli should not be nil")
@@ -51,7 +82,16 @@ func (p *PvcContext) ListPvcs() (pvcs []corev1.PersistentVolumeClaim, err error)
 
 	cli := p.k8scli
 
-	pvclist, err := cli.CoreV1().PersistentVolumeClaims(p.namespace).List(metav1.ListOptions{})
+	namespace := p.namespace
+	if opt.AllNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+
+	listOptions := metav1.ListOptions{
+		LabelSelector: opt.LabelSelector,
+		FieldSelector: opt.FieldSelector,
+	}
+	pvclist, err := cli.CoreV1().PersistentVolumeClaims(namespace).List(listOptions)
 	if err != nil {
 		return pvcs, fmt.Errorf("list pvcs from kubernetes apiserver failed, err %v", err)
 	}
@@ -61,12 +101,18 @@ func (p *PvcContext) ListPvcs() (pvcs []corev1.PersistentVolumeClaim, err error)
 	return pvcs, nil
 }
 
-func (p *PvcContext) ListPvcsByPod(podname string) (pvcs []corev1.PersistentVolumeClaim, err error) {
+// ListPvcsByPod lists pvcs used by the pod, selectors are matched by the plugin itself
+func (p *PvcContext) ListPvcsByPod(podname string, opt ListPvcsOption) (pvcs []corev1.PersistentVolumeClaim, err error) {
 	pvcs = make([]corev1.PersistentVolumeClaim, 0)
 	if p.k8scli == nil {
 		return pvcs, fmt.Errorf("PvcContext.k8scli should not be nil")
 	}
 
+	matcher, err := newSelectorMatcher(opt.LabelSelector, opt.FieldSelector)
+	if err != nil {
+		return pvcs, err
+	}
+
 	cli := p.k8scli
 
 	pod, err := cli.CoreV1().Pods(p.namespace).Get(podname, metav1.GetOptions{})
@@ -81,7 +127,9 @@ func (p *PvcContext) ListPvcsByPod(podname string) (pvcs []corev1.PersistentVolu
 			if err != nil {
 				return pvcs, fmt.Errorf("get pvc [%v/%v] info from kubernetes apiserver failed, err: %v", p.namespace, vol.PersistentVolumeClaim.ClaimName, err)
 			}
-			pvcs = append(pvcs, *pvc)
+			if matcher.matches(pvc) {
+				pvcs = append(pvcs, *pvc)
+			}
 		}
 	}
 
@@ -95,8 +143,16 @@ const (
 	PvcBind      PvcPhaseName = "Bind"
 	PvcAttach    PvcPhaseName = "Attach"
 	PvcMount     PvcPhaseName = "Mount"
+	// Resize is not a step of the lifecycle, it only happens when the pvc is expanded
+	PvcResize PvcPhaseName = "Resize"
 )
 
+// phaseOrder is the order in which a pvc goes through the phases
+var phaseOrder = []PvcPhaseName{PvcProvision, PvcBind, PvcAttach, PvcMount}
+
+// allPhases are the phases printed in the status of pvc
+var allPhases = []PvcPhaseName{PvcProvision, PvcBind, PvcAttach, PvcMount, PvcResize}
+
 type PvcPhaseStatus string
 
 const (
@@ -104,42 +160,82 @@ const (
 	PvcPhaseFail       PvcPhaseStatus = "fail"
 	PvcPhasePartlyFail PvcPhaseStatus = "partly fail"
 	PvcPhaseOndoing    PvcPhaseStatus = "ondoing"
+	// the phase is never carried out for this kind of volume, e.g. Attach of nfs
+	PvcPhaseNotApplicable PvcPhaseStatus = "not applicable"
 )
 
 type PvcPhase struct {
-	Name   PvcPhaseName
-	Status PvcPhaseStatus
-	Detail string
+	Name   PvcPhaseName   `json:"name"`
+	Status PvcPhaseStatus `json:"status"`
+	Detail string         `json:"detail,omitempty"`
+	// the newest events of this phase
+	Events []*Event `json:"events,omitempty"`
+
+	// the failure is reported explicitly and will not recover by itself, e.g. ProvisioningFailed
+	hard bool
+	// provisioning is delayed until a pod using the pvc is scheduled
+	waitForFirstConsumer bool
+	// the phase is not applicable only because no pod is using the pvc yet
+	noConsumer bool
 }
 
 type PvcStatus struct {
-	Name     string
-	PVStatus *PVStatus
-	Nodes    []*Node
-	Pods     []*Pod
-	Phases   map[PvcPhaseName]*PvcPhase
+	Name      string                     `json:"name"`
+	Namespace string                     `json:"namespace"`
+	PVStatus  *PVStatus                  `json:"pv,omitempty"`
+	Nodes     []*Node                    `json:"nodes"`
+	Pods      []*Pod                     `json:"pods"`
+	Phases    map[PvcPhaseName]*PvcPhase `json:"phases"`
+	// all events related to the pvc, sorted from the newest to the oldest
+	Events []*Event `json:"events"`
+	// problems which are not part of any phase, e.g. stale attachment on old node
+	Warnings []string `json:"warnings"`
 }
 
 type PVStatus struct {
-	Name               string
-	AttachedVolumeName string
+	Name         string `json:"name"`
+	Plugin       string `json:"plugin"`
+	VolumeHandle string `json:"volumeHandle"`
+	StorageClass string `json:"storageClass"`
+	// empty if the volume is never attached to node
+	AttachedVolumeName string `json:"attachedVolumeName,omitempty"`
+
+	uid types.UID
+	// empty if it is not a csi volume
+	driver string
 }
 
 type Node struct {
-	Name string
+	Name        string `json:"name"`
+	Attached    bool   `json:"attached"`
+	AttachError string `json:"attachError,omitempty"`
+	DetachError string `json:"detachError,omitempty"`
+	// empty if the attachment is found from volumesAttached of Node
+	VolumeAttachment string `json:"volumeAttachment,omitempty"`
+	// VolumeAttachment is being deleted
+	Detaching bool `json:"detaching"`
+	// some pod using the pvc is on this node
+	Desired bool `json:"desired"`
+	// Ready, NotReady, Unknown or Deleted, only set for stale node
+	State string `json:"state,omitempty"`
+
+	volumeAttachmentUID types.UID
 }
 
 func NewNode(n *corev1.Node) *Node {
 	return &Node{
-		Name: n.Name,
+		Name:     n.Name,
+		Attached: true,
 	}
 }
 
 type Pod struct {
-	Name      string
-	Volume    string
-	Node      string
-	PodStatus corev1.PodPhase
+	Name        string          `json:"name"`
+	Volume      string          `json:"volume"`
+	Node        string          `json:"node"`
+	PodStatus   corev1.PodPhase `json:"podPhase"`
+	MountStatus PodMountStatus  `json:"mountStatus"`
+	MountReason string          `json:"mountReason,omitempty"`
 }
 
 func NewPod(p *corev1.Pod, vol string) *Pod {
@@ -151,16 +247,30 @@ func NewPod(p *corev1.Pod, vol string) *Pod {
 	}
 }
 
-func (p *PvcContext) GetPvcDetail(pvcname string) (*PvcStatus, error) {
-	pvcStatus := &PvcStatus{
-		Name: pvcname,
+func newPvcStatus(name, namespace string) *PvcStatus {
+	return &PvcStatus{
+		Name:      name,
+		Namespace: namespace,
+		Nodes:     []*Node{},
+		Pods:      []*Pod{},
+		Events:    []*Event{},
+		Warnings:  []string{},
 		Phases: map[PvcPhaseName]*PvcPhase{
 			PvcProvision: &PvcPhase{Name: PvcProvision},
 			PvcBind:      &PvcPhase{Name: PvcBind},
 			PvcAttach:    &PvcPhase{Name: PvcAttach},
 			PvcMount:     &PvcPhase{Name: PvcMount},
+			PvcResize:    &PvcPhase{Name: PvcResize},
 		},
 	}
+}
+
+// GetPvcDetail deduces the status of pvc in every phase, namespace of context is used if namespace is empty
+func (p *PvcContext) GetPvcDetail(namespace, pvcname string) (*PvcStatus, error) {
+	if namespace == "" {
+		namespace = p.namespace
+	}
+	pvcStatus := newPvcStatus(pvcname, namespace)
 	if p.k8scli == nil {
 		return pvcStatus, fmt.Errorf("PvcContext.k8scli should not be nil")
 	}
@@ -169,79 +279,207 @@ func (p *PvcContext) GetPvcDetail(pvcname string) (*PvcStatus, error) {
 
 	// check if persisentVolumeClaim's volumeName is set
 	// if set, then it means this persistentVolumeClaim is
-	pvc, err := cli.CoreV1().PersistentVolumeClaims(p.namespace).Get(pvcname, metav1.GetOptions{})
+	pvc, err := cli.CoreV1().PersistentVolumeClaims(namespace).Get(pvcname, metav1.GetOptions{})
 	if err != nil {
-		return pvcStatus, fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", p.namespace, pvcname, err)
+		return pvcStatus, fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", namespace, pvcname, err)
 	}
 
-	pvname := pvc.Spec.VolumeName
-	if pvname == "" {
-		return pvcStatus, nil
+	podList, err := cli.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return pvcStatus, fmt.Errorf("get info about pods of namespace [%s] failed, err: %v", namespace, err)
 	}
-	pvcStatus.Phases[PvcProvision].Status = PvcPhaseSuccess
-	pvcStatus.Phases[PvcBind].Status = PvcPhaseSuccess
 
-	pv, err := cli.CoreV1().PersistentVolumes().Get(pvname, metav1.GetOptions{})
-	if err != nil {
-		return pvcStatus, fmt.Errorf("get info about pv [%s/%s] failed, err: %v", p.namespace, pvname, err)
+	return p.getPvcDetail(pvc, podList.Items, newClusterObjects(false))
+}
+
+// GetPvcsDetail deduces the status of many pvcs with pods, pvs, volumeattachments, nodes, storageclasses and
+// events listed only once, the status is nil if it fails to deduce the status of that pvc
+func (p *PvcContext) GetPvcsDetail(pvcs []corev1.PersistentVolumeClaim) ([]*PvcStatus, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
 	}
 
-	attachedVolumeName, err := getAttachedVolumeName(pv)
-	if err != nil {
-		return pvcStatus, err
+	objs := newClusterObjects(true)
+	// events of pvcs in many namespaces are listed in all namespaces at once
+	for i := range pvcs {
+		if pvcs[i].Namespace != pvcs[0].Namespace {
+			if err := p.listEvents(objs, metav1.NamespaceAll); err != nil {
+				return nil, err
+			}
+			break
+		}
 	}
+	podsOfNamespace := make(map[string][]corev1.Pod)
+	statuses := make([]*PvcStatus, 0, len(pvcs))
+	for i := range pvcs {
+		pvc := &pvcs[i]
+		pods, ok := podsOfNamespace[pvc.Namespace]
+		if !ok {
+			podList, err := p.k8scli.CoreV1().Pods(pvc.Namespace).List(metav1.ListOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("get info about pods of namespace [%s] failed, err: %v", pvc.Namespace, err)
+			}
+			pods = podList.Items
+			podsOfNamespace[pvc.Namespace] = pods
+		}
 
-	pvcStatus.PVStatus = &PVStatus{
-		Name:               pvname,
-		AttachedVolumeName: attachedVolumeName,
+		status, err := p.getPvcDetail(pvc, pods, objs)
+		if err != nil {
+			klog.Errorf("get detail of pvc [%s/%s] failed, err: %v", pvc.Namespace, pvc.Name, err)
+			status = nil
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// getPvcDetail deduces the status of pvc in every phase, pods are all pods in the namespace of pvc
+func (p *PvcContext) getPvcDetail(pvc *corev1.PersistentVolumeClaim, podList []corev1.Pod, objs *clusterObjects) (*PvcStatus, error) {
+	pvcStatus := newPvcStatus(pvc.Name, pvc.Namespace)
+	pvcname := pvc.Name
+
+	events, err := p.listPhaseEvents(objs, pvc.Namespace, pvc.UID)
+	if err != nil {
+		return pvcStatus, err
 	}
 
 	pods := make([]*Pod, 0)
 	desiredNodes := make(map[string]struct{})
-	podList, err := cli.CoreV1().Pods(p.namespace).List(metav1.ListOptions{})
-	for _, pod := range podList.Items {
+	for _, pod := range podList {
 		if flag, vol := isPvcUsedByPod(pvcname, &pod); flag {
 			np := NewPod(&pod, vol)
-			desiredNodes[pod.Spec.NodeName] = struct{}{}
+			podEvents, err := p.listPhaseEvents(objs, pvc.Namespace, pod.UID)
+			if err != nil {
+				return pvcStatus, err
+			}
+			events = append(events, podEvents...)
+
+			sortEvents(podEvents)
+			np.MountStatus, np.MountReason = deducePodMount(&pod, podEvents)
+			if np.MountStatus != PodMountTerminated && np.Node != "" {
+				desiredNodes[np.Node] = struct{}{}
+			}
 			pods = append(pods, np)
 		}
 	}
 
 	pvcStatus.Pods = pods
 
-	nodeList, err := cli.CoreV1().Nodes().List(metav1.ListOptions{})
+	provisionPhase, err := p.deducePhaseProvision(objs, pvc, pods, events)
+	if err != nil {
+		return pvcStatus, err
+	}
+	pvcStatus.Phases[PvcProvision] = provisionPhase
+
+	pvname := pvc.Spec.VolumeName
+	if pvname == "" {
+		pv, err := p.findPreBoundPv(objs, pvc)
+		if err != nil {
+			return pvcStatus, err
+		}
+		if pv != nil {
+			pvcStatus.Phases[PvcBind] = deducePhasePreBind(pvc, pv)
+		}
+		setPhaseEvents(pvcStatus, events)
+		return pvcStatus, nil
+	}
+
+	pv, err := p.getPv(objs, pvname)
 	if err != nil {
-		return pvcStatus, fmt.Errorf("get info about nodes failed, err: %v", err)
+		if apierrors.IsNotFound(err) {
+			pvcStatus.Phases[PvcBind] = deducePhaseBind(pvc, nil)
+			setPhaseEvents(pvcStatus, events)
+			return pvcStatus, nil
+		}
+		return pvcStatus, fmt.Errorf("get info about pv [%s/%s] failed, err: %v", pvc.Namespace, pvname, err)
+	}
+
+	pvcStatus.Phases[PvcBind] = deducePhaseBind(pvc, pv)
+
+	plugin, attachedVolumeName, err := getAttachedVolumeName(pv)
+	if err != nil {
+		return pvcStatus, err
+	}
+
+	pvcStatus.PVStatus = &PVStatus{
+		Name:               pvname,
+		Plugin:             plugin,
+		VolumeHandle:       getVolumeHandle(pv),
+		StorageClass:       getPvStorageClassName(pv),
+		AttachedVolumeName: attachedVolumeName,
+		uid:                pv.UID,
+	}
+	if pv.Spec.CSI != nil {
+		pvcStatus.PVStatus.driver = pv.Spec.CSI.Driver
 	}
 
-	nodes := make([]*Node, 0)
+	pvEvents, err := p.listPhaseEvents(objs, metav1.NamespaceAll, pv.UID)
+	if err != nil {
+		return pvcStatus, err
+	}
+	events = append(events, pvEvents...)
 
-	for _, node := range nodeList.Items {
-		if isPvAttachToNode(pvcStatus.PVStatus.AttachedVolumeName, &node) {
-			n := NewNode(&node)
-			nodes = append(nodes, n)
+	attachRequired := true
+	if pv.Spec.CSI != nil && attachedVolumeName != "" {
+		attachRequired, err = p.isAttachRequired(objs, pv.Spec.CSI.Driver)
+		if err != nil {
+			return pvcStatus, err
 		}
 	}
 
-	pvcStatus.Nodes = nodes
+	if pvcStatus.PVStatus.AttachedVolumeName == "" {
+		pvcStatus.Phases[PvcAttach] = &PvcPhase{
+			Name:   PvcAttach,
+			Status: PvcPhaseNotApplicable,
+			Detail: fmt.Sprintf("volume of plugin %s is not attached to node", plugin),
+		}
+	} else if !attachRequired {
+		pvcStatus.Phases[PvcAttach] = &PvcPhase{
+			Name:   PvcAttach,
+			Status: PvcPhaseNotApplicable,
+			Detail: fmt.Sprintf("csi driver %s does not require attaching volume to node", pv.Spec.CSI.Driver),
+		}
+	} else {
+		nodes, err := p.getAttachedNodes(objs, pvcStatus.PVStatus)
+		if err != nil {
+			return pvcStatus, err
+		}
 
-	attachPhase := deducePhaseAttach(nodes, desiredNodes)
-	pvcStatus.Phases[PvcAttach] = attachPhase
+		pvcStatus.Nodes = nodes
 
-	mountPhase := deducePhaseMount(pvcname, pods)
-	pvcStatus.Phases[PvcMount] = mountPhase
+		for _, node := range nodes {
+			if node.volumeAttachmentUID == "" {
+				continue
+			}
+			vaEvents, err := p.listPhaseEvents(objs, metav1.NamespaceAll, node.volumeAttachmentUID)
+			if err != nil {
+				return pvcStatus, err
+			}
+			events = append(events, vaEvents...)
+		}
 
-	return pvcStatus, nil
-}
+		for _, node := range nodes {
+			if _, ok := desiredNodes[node.Name]; ok {
+				node.Desired = true
+			}
+		}
+		if err := p.completeNodeState(objs, staleNodes(nodes)); err != nil {
+			return pvcStatus, err
+		}
+		pvcStatus.Warnings = formatStaleNodesWarnings(staleNodes(nodes))
 
-// get the name of this pv which is displayed on the volumesAttached of Node
-func getAttachedVolumeName(pv *corev1.PersistentVolume) (string, error) {
-	// Todo support none-CSI pv
-	if pv.Spec.CSI == nil {
-		return "", fmt.Errorf("pv which is not based on csi is now not supported")
+		attachPhase := deducePhaseAttach(nodes, desiredNodes, isReadWriteOnce(pvc))
+		pvcStatus.Phases[PvcAttach] = attachPhase
 	}
 
-	return pv.Spec.CSI.VolumeHandle, nil
+	mountPhase := deducePhaseMount(pods)
+	pvcStatus.Phases[PvcMount] = mountPhase
+
+	pvcStatus.Phases[PvcResize] = deducePhaseResize(pvc, pv, pods, events)
+
+	setPhaseEvents(pvcStatus, events)
+
+	return pvcStatus, nil
 }
 
 func isPvcUsedByPod(pvc string, p *corev1.Pod) (bool, string) {
@@ -254,50 +492,69 @@ func isPvcUsedByPod(pvc string, p *corev1.Pod) (bool, string) {
 	return false, ""
 }
 
-// Todo: more precise way to determine the volume is mounted successfully to Pod
-func isPvcMountedToPod(pvc string, pod *Pod) bool {
-	if pod.PodStatus == corev1.PodPending {
-		return false
-	}
-	return true
-}
-
-// Todo: consider non-CSI persistent volume
 func isPvAttachToNode(name string, n *corev1.Node) bool {
 	// klog.Infof("check pvc %s is attached by node %s", name, n.Name)
 	for _, vol := range n.Status.VolumesAttached {
-		if strings.Contains(string(vol.Name), name) {
+		if string(vol.Name) == name {
 			return true
 		}
 	}
 	return false
 }
 
-func deducePhaseAttach(attachedNodes []*Node, desiredNodes map[string]struct{}) *PvcPhase {
+func deducePhaseAttach(attachedNodes []*Node, desiredNodes map[string]struct{}, readWriteOnce bool) *PvcPhase {
+	unattached := make(map[string]struct{})
+	for node := range desiredNodes {
+		unattached[node] = struct{}{}
+	}
+
 	partly := false
+	errs := make([]string, 0)
 	for _, node := range attachedNodes {
-		if _, ok := desiredNodes[node.Name]; ok {
-			delete(desiredNodes, node.Name)
+		if node.AttachError != "" {
+			errs = append(errs, fmt.Sprintf("node %s attach error: %s", node.Name, node.AttachError))
+		}
+		if node.DetachError != "" {
+			errs = append(errs, fmt.Sprintf("node %s detach error: %s", node.Name, node.DetachError))
+		}
+		if !node.Attached {
+			continue
+		}
+		if _, ok := unattached[node.Name]; ok {
+			delete(unattached, node.Name)
 			partly = true
 		}
-		// Todo if volume is not deattached from old node
 	}
 
 	p := &PvcPhase{
 		Name: PvcAttach,
 	}
 
-	if len(desiredNodes) > 0 {
+	if len(desiredNodes) == 0 {
+		p.Status = PvcPhaseNotApplicable
+		p.Detail = strings.Join(append([]string{"no pod using this pvc is scheduled to node"}, errs...), "; ")
+		p.noConsumer = true
+		return p
+	}
+
+	if len(unattached) > 0 {
 		// it means it has some volume not attached to desired nodes
 		if partly {
 			p.Status = PvcPhasePartlyFail
 		} else {
 			p.Status = PvcPhaseFail
 		}
-		p.Detail = formatUnattachedNodesMsg(desiredNodes)
+		msgs := []string{formatUnattachedNodesMsg(unattached)}
+		// attach/detach controller refuses to attach ReadWriteOnce volume to another node
+		// before it is detached from the old one
+		if stale := staleNodes(attachedNodes); readWriteOnce && len(stale) > 0 {
+			msgs = append(msgs, fmt.Sprintf("volume is ReadWriteOnce and still attached to nodes: [%s], which blocks attaching to desired nodes", strings.Join(formatNodesWithState(stale), ",")))
+		}
+		errs = append(msgs, errs...)
 	} else {
 		p.Status = PvcPhaseSuccess
 	}
+	p.Detail = strings.Join(errs, "; ")
 
 	return p
 }
@@ -310,14 +567,18 @@ func formatUnattachedNodesMsg(nodes map[string]struct{}) string {
 	return fmt.Sprintf("nodes: [%s] are still not attached as desired", strings.Join(n, ","))
 }
 
-func deducePhaseMount(pvc string, pods []*Pod) *PvcPhase {
-	partly := false
-	fp := make([]string, 0)
+func deducePhaseMount(pods []*Pod) *PvcPhase {
+	mounted := 0
+	failed := make([]string, 0)
+	waiting := make([]string, 0)
 	for _, pod := range pods {
-		if !isPvcMountedToPod(pvc, pod) {
-			fp = append(fp, pod.Name)
-		} else {
-			partly = true
+		switch pod.MountStatus {
+		case PodMountMounted:
+			mounted++
+		case PodMountFailed:
+			failed = append(failed, formatPodMountMsg(pod))
+		case PodMountWaiting:
+			waiting = append(waiting, formatPodMountMsg(pod))
 		}
 	}
 
@@ -325,21 +586,27 @@ func deducePhaseMount(pvc string, pods []*Pod) *PvcPhase {
 		Name: PvcMount,
 	}
 
-	if len(fp) > 0 {
-		if partly {
-			p.Status = PvcPhasePartlyFail
-		} else {
-			p.Status = PvcPhaseFail
-		}
-		p.Detail = formatUnmountedPodsMsg(fp)
-	} else {
+	switch {
+	case len(failed) > 0 && (mounted > 0 || len(waiting) > 0):
+		p.Status = PvcPhasePartlyFail
+	case len(failed) > 0:
+		p.Status = PvcPhaseFail
+	case len(waiting) > 0:
+		p.Status = PvcPhaseOndoing
+	case mounted > 0:
 		p.Status = PvcPhaseSuccess
+	default:
+		p.Status = PvcPhaseNotApplicable
+		p.Detail = "no running pod is using this pvc"
+		p.noConsumer = true
+		return p
 	}
+	p.Detail = strings.Join(append(failed, waiting...), "; ")
 	return p
 }
 
-func formatUnmountedPodsMsg(pods []string) string {
-	return fmt.Sprintf("pods: [%s] are still not mounted as desired", strings.Join(pods, ","))
+func formatPodMountMsg(pod *Pod) string {
+	return fmt.Sprintf("pod %s %s: %s", pod.Name, pod.MountStatus, pod.MountReason)
 }
 
 func (p *PvcContext) GetNamespace() string {
@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PvcOverrides overrides the spec copied from the source when a pvc is created from a snapshot or another pvc,
+// empty fields are not overridden
+type PvcOverrides struct {
+	StorageClass string
+	AccessModes  []string
+	VolumeMode   string
+	Size         string
+}
+
+// Validate parses the overrides which are given by user
+func (o *PvcOverrides) Validate() error {
+	for _, mode := range o.AccessModes {
+		if parseAccessMode(mode) == "" {
+			return fmt.Errorf("unsupported access mode %q, should be one of: RWO|ROX|RWX|RWOP", mode)
+		}
+	}
+	switch corev1.PersistentVolumeMode(o.VolumeMode) {
+	case "", corev1.PersistentVolumeFilesystem, corev1.PersistentVolumeBlock:
+	default:
+		return fmt.Errorf("unsupported volume mode %q, should be one of: Filesystem|Block", o.VolumeMode)
+	}
+	if o.Size != "" {
+		if _, err := resource.ParseQuantity(o.Size); err != nil {
+			return fmt.Errorf("invalid size %q, err: %v", o.Size, err)
+		}
+	}
+	return nil
+}
+
+// newPvcFromSource builds a pvc with the same storageclass, access modes, volume mode and size as the source,
+// size is raised to minSize if it is smaller, source is nil if it does not exist any more
+func newPvcFromSource(namespace, name string, source *corev1.PersistentVolumeClaim, minSize resource.Quantity,
+	dataSource *corev1.TypedLocalObjectReference, o PvcOverrides) (*corev1.PersistentVolumeClaim, error) {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			DataSource: dataSource,
+		},
+	}
+
+	size := minSize.DeepCopy()
+	if source != nil {
+		if class, ok := getPvcStorageClassName(source); ok {
+			pvc.Spec.StorageClassName = &class
+		}
+		pvc.Spec.AccessModes = source.Spec.AccessModes
+		pvc.Spec.VolumeMode = source.Spec.VolumeMode
+		if request, ok := source.Spec.Resources.Requests[corev1.ResourceStorage]; ok && request.Cmp(size) > 0 {
+			size = request
+		}
+	}
+
+	if o.StorageClass != "" {
+		pvc.Spec.StorageClassName = &o.StorageClass
+	}
+	if len(o.AccessModes) > 0 {
+		pvc.Spec.AccessModes = make([]corev1.PersistentVolumeAccessMode, 0, len(o.AccessModes))
+		for _, mode := range o.AccessModes {
+			pvc.Spec.AccessModes = append(pvc.Spec.AccessModes, parseAccessMode(mode))
+		}
+	}
+	if o.VolumeMode != "" {
+		mode := corev1.PersistentVolumeMode(o.VolumeMode)
+		pvc.Spec.VolumeMode = &mode
+	}
+	if o.Size != "" {
+		override := resource.MustParse(o.Size)
+		if override.Cmp(minSize) < 0 {
+			return nil, fmt.Errorf("size %s is smaller than the size of source %s", override.String(), minSize.String())
+		}
+		size = override
+	}
+
+	if len(pvc.Spec.AccessModes) == 0 {
+		return nil, fmt.Errorf("access modes of pvc [%s/%s] are unknown, they should be given explicitly", namespace, name)
+	}
+	if size.IsZero() {
+		return nil, fmt.Errorf("size of pvc [%s/%s] is unknown, it should be given explicitly", namespace, name)
+	}
+	pvc.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: size}
+	return pvc, nil
+}
@@ -0,0 +1,222 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// the subset of stats summary of kubelet about volumes, see k8s.io/kubelet/pkg/apis/stats/v1alpha1
+type statsSummary struct {
+	Pods []podStats `json:"pods"`
+}
+
+type podStats struct {
+	PodRef struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"podRef"`
+	VolumeStats []volumeStats `json:"volume"`
+}
+
+type volumeStats struct {
+	Name   string `json:"name"`
+	PVCRef *struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"pvcRef"`
+	AvailableBytes *uint64 `json:"availableBytes"`
+	CapacityBytes  *uint64 `json:"capacityBytes"`
+	UsedBytes      *uint64 `json:"usedBytes"`
+	InodesFree     *uint64 `json:"inodesFree"`
+	Inodes         *uint64 `json:"inodes"`
+	InodesUsed     *uint64 `json:"inodesUsed"`
+}
+
+// PvcUsage is the filesystem usage of pvc reported by kubelet
+type PvcUsage struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Node      string `json:"node"`
+	// pods using the pvc on the node
+	Pods           []string `json:"pods"`
+	UsedBytes      uint64   `json:"usedBytes"`
+	AvailableBytes uint64   `json:"availableBytes"`
+	CapacityBytes  uint64   `json:"capacityBytes"`
+	InodesUsed     uint64   `json:"inodesUsed"`
+	InodesFree     uint64   `json:"inodesFree"`
+	Inodes         uint64   `json:"inodes"`
+}
+
+func NewPvcUsage(vs *volumeStats, node string) *PvcUsage {
+	return &PvcUsage{
+		Name:           vs.PVCRef.Name,
+		Namespace:      vs.PVCRef.Namespace,
+		Node:           node,
+		Pods:           []string{},
+		UsedBytes:      uint64Value(vs.UsedBytes),
+		AvailableBytes: uint64Value(vs.AvailableBytes),
+		CapacityBytes:  uint64Value(vs.CapacityBytes),
+		InodesUsed:     uint64Value(vs.InodesUsed),
+		InodesFree:     uint64Value(vs.InodesFree),
+		Inodes:         uint64Value(vs.Inodes),
+	}
+}
+
+// uint64Value returns 0 if the stat is not reported
+func uint64Value(v *uint64) uint64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// UsedPercent returns the percentage of used bytes, or -1 if the capacity is unknown
+func (u *PvcUsage) UsedPercent() float64 {
+	return percent(u.UsedBytes, u.CapacityBytes)
+}
+
+// InodesUsedPercent returns the percentage of used inodes, or -1 if the number of inodes is unknown
+func (u *PvcUsage) InodesUsedPercent() float64 {
+	return percent(u.InodesUsed, u.Inodes)
+}
+
+func percent(used, total uint64) float64 {
+	if total == 0 {
+		return -1
+	}
+	return float64(used) * 100 / float64(total)
+}
+
+const (
+	SortByPercent = "percent"
+	SortByUsed    = "used"
+	SortByInodes  = "inodes"
+)
+
+type GetPvcsUsageOption struct {
+	AllNamespaces bool
+	// only the pvcs used by the pod if not empty
+	Pod string
+	// one of percent, used or inodes
+	SortBy string
+}
+
+// GetPvcsUsage reads the stats summary of kubelet through the node proxy of apiserver on every node
+// hosting pods which use pvcs, only the pvcs mounted by some running pod can be found
+func (p *PvcContext) GetPvcsUsage(opt GetPvcsUsageOption) ([]*PvcUsage, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+
+	namespace := p.namespace
+	if opt.AllNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+
+	pods := make([]corev1.Pod, 0)
+	if opt.Pod != "" {
+		pod, err := p.k8scli.CoreV1().Pods(namespace).Get(opt.Pod, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("get pod [%v/%v] info from kubernetes apiserver failed, err: %v", namespace, opt.Pod, err)
+		}
+		pods = append(pods, *pod)
+	} else {
+		podList, err := p.k8scli.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("get info about pods of namespace [%s] failed, err: %v", namespace, err)
+		}
+		pods = podList.Items
+	}
+
+	// pods using pvcs of every node, keyed by namespace/name
+	nodes := make(map[string]map[string]bool)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || !podUsesPvc(&pod) {
+			continue
+		}
+		if nodes[pod.Spec.NodeName] == nil {
+			nodes[pod.Spec.NodeName] = make(map[string]bool)
+		}
+		nodes[pod.Spec.NodeName][pod.Namespace+"/"+pod.Name] = true
+	}
+
+	nodenames := make([]string, 0, len(nodes))
+	for node := range nodes {
+		nodenames = append(nodenames, node)
+	}
+	sort.Strings(nodenames)
+
+	usages := make([]*PvcUsage, 0)
+	// a pvc shared by pods on several nodes is only reported once
+	found := make(map[string]*PvcUsage)
+	for _, node := range nodenames {
+		summary, err := p.getStatsSummary(node)
+		if err != nil {
+			klog.Errorf("get volume stats of node [%s] failed, err: %v", node, err)
+			continue
+		}
+		for _, ps := range summary.Pods {
+			if !nodes[node][ps.PodRef.Namespace+"/"+ps.PodRef.Name] {
+				continue
+			}
+			for i := range ps.VolumeStats {
+				vs := &ps.VolumeStats[i]
+				if vs.PVCRef == nil {
+					continue
+				}
+				key := vs.PVCRef.Namespace + "/" + vs.PVCRef.Name
+				u, ok := found[key]
+				if !ok {
+					u = NewPvcUsage(vs, node)
+					found[key] = u
+					usages = append(usages, u)
+				}
+				u.Pods = append(u.Pods, ps.PodRef.Name)
+			}
+		}
+	}
+
+	sortPvcUsages(usages, opt.SortBy)
+	return usages, nil
+}
+
+func podUsesPvc(pod *corev1.Pod) bool {
+	for _, vol := range pod.Spec.Volumes {
+		if vol.PersistentVolumeClaim != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PvcContext) getStatsSummary(node string) (*statsSummary, error) {
+	data, err := p.k8scli.CoreV1().RESTClient().Get().
+		Resource("nodes").Name(node).SubResource("proxy").Suffix("stats/summary").
+		DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	summary := &statsSummary{}
+	if err := json.Unmarshal(data, summary); err != nil {
+		return nil, fmt.Errorf("decode stats summary failed, err: %v", err)
+	}
+	return summary, nil
+}
+
+// sortPvcUsages sorts pvcs from the most used to the least used
+func sortPvcUsages(usages []*PvcUsage, sortBy string) {
+	sort.SliceStable(usages, func(i, j int) bool {
+		switch sortBy {
+		case SortByUsed:
+			return usages[i].UsedBytes > usages[j].UsedBytes
+		case SortByInodes:
+			return usages[i].InodesUsedPercent() > usages[j].InodesUsedPercent()
+		}
+		return usages[i].UsedPercent() > usages[j].UsedPercent()
+	})
+}
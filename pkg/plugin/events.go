@@ -0,0 +1,174 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// reasons of events which are recorded by pv controller, attach/detach controller, expand controller,
+// external provisioner/attacher/resizer and kubelet during the lifecycle of pvc
+const (
+	eventReasonProvisioningFailed    = "ProvisioningFailed"
+	eventReasonProvisioningSucceeded = "ProvisioningSucceeded"
+	eventReasonExternalProvisioning  = "ExternalProvisioning"
+	eventReasonProvisioning          = "Provisioning"
+	eventReasonProvisioningCleanup   = "ProvisioningCleanupFailed"
+	eventReasonWaitForFirstConsumer  = "WaitForFirstConsumer"
+	eventReasonFailedBinding         = "FailedBinding"
+	eventReasonVolumeMismatch        = "VolumeMismatch"
+	eventReasonClaimLost             = "ClaimLost"
+	eventReasonClaimMisbound         = "ClaimMisbound"
+	eventReasonFailedAttachVolume    = "FailedAttachVolume"
+	eventReasonFailedDetachVolume    = "FailedDetachVolume"
+	eventReasonSuccessfulAttach      = "SuccessfulAttachVolume"
+	eventReasonSuccessfulDetach      = "SuccessfulDetachVolume"
+	eventReasonFailedMount           = "FailedMount"
+	eventReasonFailedMapVolume       = "FailedMapVolume"
+	eventReasonSuccessfulMount       = "SuccessfulMountVolume"
+	eventReasonSuccessfulMapVolume   = "SuccessfulMapVolume"
+	eventReasonResizing              = "Resizing"
+	eventReasonExternalExpanding     = "ExternalExpanding"
+	eventReasonVolumeResizeFailed    = "VolumeResizeFailed"
+	eventReasonVolumeResizeSucceeded = "VolumeResizeSuccessful"
+	eventReasonFSResizeRequired      = "FileSystemResizeRequired"
+	eventReasonFSResizeFailed        = "FileSystemResizeFailed"
+	eventReasonFSResizeSucceeded     = "FileSystemResizeSuccessful"
+)
+
+var eventReasonPhases = map[string]PvcPhaseName{
+	eventReasonProvisioningFailed:    PvcProvision,
+	eventReasonProvisioningSucceeded: PvcProvision,
+	eventReasonExternalProvisioning:  PvcProvision,
+	eventReasonProvisioning:          PvcProvision,
+	eventReasonProvisioningCleanup:   PvcProvision,
+	eventReasonWaitForFirstConsumer:  PvcProvision,
+	eventReasonFailedBinding:         PvcBind,
+	eventReasonVolumeMismatch:        PvcBind,
+	eventReasonClaimLost:             PvcBind,
+	eventReasonClaimMisbound:         PvcBind,
+	eventReasonFailedAttachVolume:    PvcAttach,
+	eventReasonFailedDetachVolume:    PvcAttach,
+	eventReasonSuccessfulAttach:      PvcAttach,
+	eventReasonSuccessfulDetach:      PvcAttach,
+	eventReasonFailedMount:           PvcMount,
+	eventReasonFailedMapVolume:       PvcMount,
+	eventReasonSuccessfulMount:       PvcMount,
+	eventReasonSuccessfulMapVolume:   PvcMount,
+	eventReasonResizing:              PvcResize,
+	eventReasonExternalExpanding:     PvcResize,
+	eventReasonVolumeResizeFailed:    PvcResize,
+	eventReasonVolumeResizeSucceeded: PvcResize,
+	eventReasonFSResizeRequired:      PvcResize,
+	eventReasonFSResizeFailed:        PvcResize,
+	eventReasonFSResizeSucceeded:     PvcResize,
+}
+
+// how many of the newest events are kept in each phase
+const maxPhaseEvents = 3
+
+type Event struct {
+	Phase         PvcPhaseName `json:"phase"`
+	Object        string       `json:"object"`
+	Type          string       `json:"type"`
+	Reason        string       `json:"reason"`
+	Message       string       `json:"message"`
+	Count         int32        `json:"count"`
+	LastTimestamp time.Time    `json:"lastTimestamp"`
+}
+
+func NewEvent(e *corev1.Event, phase PvcPhaseName) *Event {
+	last := e.LastTimestamp.Time
+	if last.IsZero() {
+		last = e.EventTime.Time
+	}
+	if last.IsZero() {
+		last = e.FirstTimestamp.Time
+	}
+	return &Event{
+		Phase:         phase,
+		Object:        fmt.Sprintf("%s/%s", e.InvolvedObject.Kind, e.InvolvedObject.Name),
+		Type:          e.Type,
+		Reason:        e.Reason,
+		Message:       e.Message,
+		Count:         e.Count,
+		LastTimestamp: last,
+	}
+}
+
+// listPhaseEvents returns the events of the object which can be classified into one lifecycle phase,
+// namespace should be empty for cluster scoped object
+func (p *PvcContext) listPhaseEvents(objs *clusterObjects, namespace string, uid types.UID) ([]*Event, error) {
+	if objs.prefetch {
+		// events of cluster scoped objects are recorded in the default namespace
+		if namespace == metav1.NamespaceAll {
+			namespace = metav1.NamespaceDefault
+		}
+		if err := p.listEvents(objs, namespace); err != nil {
+			return nil, err
+		}
+		// the caller may append to or sort the events
+		return append([]*Event{}, objs.eventsByUID[uid]...), nil
+	}
+
+	selector := fields.Set{"involvedObject.uid": string(uid)}.AsSelector().String()
+
+	eventList, err := p.k8scli.CoreV1().Events(namespace).List(metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("list events from kubernetes apiserver failed, err %v", err)
+	}
+
+	events := make([]*Event, 0)
+	for i := range eventList.Items {
+		e := &eventList.Items[i]
+		if phase, ok := eventReasonPhases[e.Reason]; ok {
+			events = append(events, NewEvent(e, phase))
+		}
+	}
+	return events, nil
+}
+
+// sortEvents sorts events from the newest to the oldest
+func sortEvents(events []*Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastTimestamp.After(events[j].LastTimestamp)
+	})
+}
+
+// latestEventOfReason returns the newest event with given reason, events should be sorted
+func latestEventOfReason(events []*Event, reason string) *Event {
+	for _, e := range events {
+		if e.Reason == reason {
+			return e
+		}
+	}
+	return nil
+}
+
+// setPhaseEvents saves all events to pvcStatus and attaches the newest events of each phase to it
+func setPhaseEvents(pvcStatus *PvcStatus, events []*Event) {
+	sortEvents(events)
+	pvcStatus.Events = events
+	for _, e := range events {
+		phase, ok := pvcStatus.Phases[e.Phase]
+		if !ok || len(phase.Events) >= maxPhaseEvents {
+			continue
+		}
+		phase.Events = append(phase.Events, e)
+	}
+}
+
+// latestWarning returns the newest warning event of the phase, nil if there is none
+func (phase *PvcPhase) latestWarning() *Event {
+	for _, e := range phase.Events {
+		if e.Type == corev1.EventTypeWarning {
+			return e
+		}
+	}
+	return nil
+}
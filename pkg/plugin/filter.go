@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// selectorMatcher matches label and field selectors on pvc which are not sent to kubernetes apiserver
+type selectorMatcher struct {
+	label labels.Selector
+	field fields.Selector
+}
+
+func newSelectorMatcher(labelSelector, fieldSelector string) (*selectorMatcher, error) {
+	label, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("parse label selector %q failed, err: %v", labelSelector, err)
+	}
+	field, err := fields.ParseSelector(fieldSelector)
+	if err != nil {
+		return nil, fmt.Errorf("parse field selector %q failed, err: %v", fieldSelector, err)
+	}
+	return &selectorMatcher{label: label, field: field}, nil
+}
+
+// matches supports the same fields as kubernetes apiserver does for pvc
+func (m *selectorMatcher) matches(pvc *corev1.PersistentVolumeClaim) bool {
+	pvcFields := fields.Set{
+		"metadata.name":      pvc.Name,
+		"metadata.namespace": pvc.Namespace,
+	}
+	return m.label.Matches(labels.Set(pvc.Labels)) && m.field.Matches(pvcFields)
+}
+
+// PvcFilter filters pvcs by the attributes which can not be selected by kubernetes apiserver
+type PvcFilter struct {
+	StorageClass string
+	// Pending, Bound or Lost
+	Phase string
+	// only pvcs which are not bound to any pv
+	Unbound bool
+	// in full name like ReadWriteOnce or in short like RWO
+	AccessMode string
+	// only pvcs with any lifecycle phase not successful, it needs the status of pvcs
+	Unhealthy bool
+}
+
+func (f *PvcFilter) Validate() error {
+	switch corev1.PersistentVolumeClaimPhase(f.Phase) {
+	case "", corev1.ClaimPending, corev1.ClaimBound, corev1.ClaimLost:
+	default:
+		return fmt.Errorf("unsupported phase %q, allowed phases are: Pending,Bound,Lost", f.Phase)
+	}
+	if f.AccessMode != "" && parseAccessMode(f.AccessMode) == "" {
+		return fmt.Errorf("unsupported access mode %q, allowed access modes are: RWO,ROX,RWX,RWOP or in full name", f.AccessMode)
+	}
+	return nil
+}
+
+// Filter returns the pvcs matching all conditions, statuses should be aligned with pvcs
+// and they are only needed when Unhealthy is set
+func (f *PvcFilter) Filter(pvcs []corev1.PersistentVolumeClaim, statuses []*PvcStatus) ([]corev1.PersistentVolumeClaim, []*PvcStatus) {
+	filteredPvcs := make([]corev1.PersistentVolumeClaim, 0, len(pvcs))
+	filteredStatuses := make([]*PvcStatus, 0, len(statuses))
+	for i := range pvcs {
+		var status *PvcStatus
+		if i < len(statuses) {
+			status = statuses[i]
+		}
+		if !f.matches(&pvcs[i], status) {
+			continue
+		}
+		filteredPvcs = append(filteredPvcs, pvcs[i])
+		if statuses != nil {
+			filteredStatuses = append(filteredStatuses, status)
+		}
+	}
+	if statuses == nil {
+		filteredStatuses = nil
+	}
+	return filteredPvcs, filteredStatuses
+}
+
+func (f *PvcFilter) matches(pvc *corev1.PersistentVolumeClaim, status *PvcStatus) bool {
+	if f.StorageClass != "" {
+		if class, _ := getPvcStorageClassName(pvc); class != f.StorageClass {
+			return false
+		}
+	}
+	if f.Phase != "" && string(pvc.Status.Phase) != f.Phase {
+		return false
+	}
+	if f.Unbound && pvc.Spec.VolumeName != "" {
+		return false
+	}
+	if f.AccessMode != "" && !containsAccessMode(pvc.Spec.AccessModes, parseAccessMode(f.AccessMode)) {
+		return false
+	}
+	if f.Unhealthy && IsPvcHealthy(status) {
+		return false
+	}
+	return true
+}
+
+// IsPvcHealthy returns true if every lifecycle phase is successful or not applicable
+func IsPvcHealthy(status *PvcStatus) bool {
+	if status == nil {
+		return false
+	}
+	for _, phase := range status.Phases {
+		if phase.Status != PvcPhaseSuccess && phase.Status != PvcPhaseNotApplicable {
+			return false
+		}
+	}
+	return true
+}
+
+func parseAccessMode(mode string) corev1.PersistentVolumeAccessMode {
+	switch mode {
+	case "RWO", string(corev1.ReadWriteOnce):
+		return corev1.ReadWriteOnce
+	case "ROX", string(corev1.ReadOnlyMany):
+		return corev1.ReadOnlyMany
+	case "RWX", string(corev1.ReadWriteMany):
+		return corev1.ReadWriteMany
+	case "RWOP", string(accessModeReadWriteOncePod):
+		return accessModeReadWriteOncePod
+	}
+	return ""
+}
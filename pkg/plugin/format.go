@@ -3,42 +3,451 @@ package plugin
 import (
 	"fmt"
 	"io"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 )
 
-func Format(out io.Writer, pvcs []corev1.PersistentVolumeClaim) {
+type FormatOption struct {
+	// add the info about pv and attached nodes
+	Wide bool
+	// add the namespace column when pvcs are from all namespaces
+	WithNamespace bool
+}
+
+// Format prints pvcs as table together with the lifecycle summary deduced in the same way as inspect,
+// statuses should be aligned with pvcs
+func Format(out io.Writer, pvcs []corev1.PersistentVolumeClaim, statuses []*PvcStatus, opt FormatOption) {
 	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
-	fmt.Fprintln(w, "NAME\tVOLUME")
-	for _, pvc := range pvcs {
-		s := formatPvc(pvc)
+	header := "NAME\tSTATUS\tVOLUME\tREQUEST\tCAPACITY\tACCESS MODES\tSTORAGECLASS\tVOLUMEMODE\tAGE\tLIFECYCLE\tUSED BY"
+	if opt.WithNamespace {
+		header = fmt.Sprintf("NAMESPACE\t%s", header)
+	}
+	if opt.Wide {
+		header = fmt.Sprintf("%s\tPLUGIN\tVOLUME HANDLE\tNODES", header)
+	}
+	fmt.Fprintln(w, header)
+	for i, pvc := range pvcs {
+		var status *PvcStatus
+		if i < len(statuses) {
+			status = statuses[i]
+		}
+		s := fmt.Sprintf("%s\t%s\t%s", formatPvc(pvc), formatLifecycle(status), formatUsedBy(status))
+		if opt.WithNamespace {
+			s = fmt.Sprintf("%s\t%s", pvc.Namespace, s)
+		}
+		if opt.Wide {
+			s = fmt.Sprintf("%s\t%s", s, formatPvcWide(status))
+		}
 		fmt.Fprintln(w, s)
 	}
 	w.Flush()
 }
 
 func formatPvc(pvc corev1.PersistentVolumeClaim) string {
-	return fmt.Sprintf("%s\t%s", pvc.Name, pvc.Spec.VolumeName)
+	request := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity := ""
+	if storage, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+		capacity = storage.String()
+	}
+	class, _ := getPvcStorageClassName(&pvc)
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s", pvc.Name, pvc.Status.Phase, pvc.Spec.VolumeName, request.String(), capacity,
+		formatAccessModes(pvc.Spec.AccessModes), class, getVolumeMode(pvc.Spec.VolumeMode), formatAge(pvc.CreationTimestamp.Time))
+}
+
+var lifecycleMarks = map[PvcPhaseStatus]string{
+	PvcPhaseSuccess:       "✓",
+	PvcPhaseFail:          "✗",
+	PvcPhasePartlyFail:    "✗",
+	PvcPhaseOndoing:       "~",
+	PvcPhaseNotApplicable: "-",
+}
+
+// formatLifecycle prints the status of all phases in short, e.g. P✓ B✓ A✗ M-,
+// ✓ is success, ✗ is fail or partly fail, ~ is ondoing, - is not applicable or not started,
+// Resize is only printed when the pvc is being expanded or the expansion failed
+func formatLifecycle(status *PvcStatus) string {
+	if status == nil {
+		return "<unknown>"
+	}
+	marks := make([]string, 0, 5)
+	for _, name := range phaseOrder {
+		mark, ok := lifecycleMarks[status.Phases[name].Status]
+		if !ok {
+			mark = "-"
+		}
+		marks = append(marks, string(name[0])+mark)
+	}
+	if resize := status.Phases[PvcResize]; resize.Status == PvcPhaseOndoing || resize.Status == PvcPhaseFail {
+		marks = append(marks, "R"+lifecycleMarks[resize.Status])
+	}
+	return strings.Join(marks, " ")
+}
+
+func formatUsedBy(status *PvcStatus) string {
+	if status == nil || len(status.Pods) == 0 {
+		return "<none>"
+	}
+	return strings.Join(podNames(status.Pods), ",")
 }
 
-func FormatPvcDetail(out io.Writer, status *PvcStatus) {
+func formatPvcWide(status *PvcStatus) string {
+	if status == nil || status.PVStatus == nil {
+		return "<none>\t<none>\t<none>"
+	}
+	nodes := make([]string, 0, len(status.Nodes))
+	for _, node := range status.Nodes {
+		if node.Attached {
+			nodes = append(nodes, node.Name)
+		}
+	}
+	if len(nodes) == 0 {
+		nodes = append(nodes, "<none>")
+	}
+	return fmt.Sprintf("%s\t%s\t%s", status.PVStatus.Plugin, status.PVStatus.VolumeHandle, strings.Join(nodes, ","))
+}
+
+// formatAccessModes prints access modes in short the same way as kubectl, e.g. RWO,ROX
+func formatAccessModes(modes []corev1.PersistentVolumeAccessMode) string {
+	short := make([]string, 0, len(modes))
+	for _, mode := range modes {
+		switch mode {
+		case corev1.ReadWriteOnce:
+			short = append(short, "RWO")
+		case corev1.ReadOnlyMany:
+			short = append(short, "ROX")
+		case corev1.ReadWriteMany:
+			short = append(short, "RWX")
+		case accessModeReadWriteOncePod:
+			short = append(short, "RWOP")
+		}
+	}
+	return strings.Join(short, ",")
+}
+
+// FormatPvcDetail prints the status of pvc as tables, wide adds info about pv and phase of pods
+func FormatPvcDetail(out io.Writer, status *PvcStatus, wide bool) {
 	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
-	fmt.Fprintln(w, "DESIRED POD\tDESIRED NODE")
+	if wide && status.PVStatus != nil {
+		fmt.Fprintln(w, "PV\tVOLUME HANDLE\tSTORAGECLASS\tPLUGIN")
+		pv := status.PVStatus
+		s := fmt.Sprintf("%s\t%s\t%s\t%s", pv.Name, pv.VolumeHandle, pv.StorageClass, pv.Plugin)
+		fmt.Fprintln(w, s)
+		w.Flush()
+		w = tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	}
+	if wide {
+		fmt.Fprintln(w, "DESIRED POD\tDESIRED NODE\tMOUNT\tPOD PHASE")
+	} else {
+		fmt.Fprintln(w, "DESIRED POD\tDESIRED NODE\tMOUNT")
+	}
 	for _, pod := range status.Pods {
-		s := fmt.Sprintf("%s\t%s", pod.Name, pod.Node)
+		s := fmt.Sprintf("%s\t%s\t%s", pod.Name, pod.Node, pod.MountStatus)
+		if wide {
+			s = fmt.Sprintf("%s\t%s", s, pod.PodStatus)
+		}
 		fmt.Fprintln(w, s)
 	}
 	w.Flush()
+	if len(status.Nodes) > 0 {
+		w = tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+		fmt.Fprintln(w, "NODE\tATTACHED\tDESIRED")
+		for _, node := range status.Nodes {
+			s := fmt.Sprintf("%s\t%t\t%t", node.Name, node.Attached, node.Desired)
+			fmt.Fprintln(w, s)
+		}
+		w.Flush()
+	}
 	w = tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
 	fmt.Fprintln(w, "PHASE\tSTATUS\tDETAIL")
-	phaseProvision := fmt.Sprintf("%s\t%s\t%s", PvcProvision, string(status.Phases[PvcProvision].Status), status.Phases[PvcProvision].Detail)
-	fmt.Fprintln(w, phaseProvision)
-	phaseBind := fmt.Sprintf("%s\t%s\t%s", PvcBind, string(status.Phases[PvcBind].Status), status.Phases[PvcBind].Detail)
-	fmt.Fprintln(w, phaseBind)
-	phaseAttach := fmt.Sprintf("%s\t%s\t%s", PvcAttach, string(status.Phases[PvcAttach].Status), status.Phases[PvcAttach].Detail)
-	fmt.Fprintln(w, phaseAttach)
-	phaseMount := fmt.Sprintf("%s\t%s\t%s", PvcMount, string(status.Phases[PvcMount].Status), status.Phases[PvcMount].Detail)
-	fmt.Fprintln(w, phaseMount)
+	for _, name := range allPhases {
+		phase := status.Phases[name]
+		s := fmt.Sprintf("%s\t%s\t%s", name, string(phase.Status), formatPhaseDetail(phase))
+		fmt.Fprintln(w, s)
+	}
+	w.Flush()
+	for _, warning := range status.Warnings {
+		fmt.Fprintf(out, "WARNING: %s\n", warning)
+	}
+}
+
+// the newest warning event is shown together with the detail if the phase is not successful
+func formatPhaseDetail(phase *PvcPhase) string {
+	if phase.Status == PvcPhaseSuccess {
+		return phase.Detail
+	}
+	e := phase.latestWarning()
+	if e == nil {
+		return phase.Detail
+	}
+	msg := fmt.Sprintf("last event %s: %s", e.Reason, firstLine(e.Message))
+	if phase.Detail == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s; %s", phase.Detail, msg)
+}
+
+func FormatPvcEvents(out io.Writer, status *PvcStatus) {
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "LAST SEEN\tPHASE\tOBJECT\tTYPE\tREASON\tCOUNT\tMESSAGE")
+	for _, e := range status.Events {
+		s := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%d\t%s", formatAge(e.LastTimestamp), e.Phase, e.Object, e.Type, e.Reason, e.Count, firstLine(e.Message))
+		fmt.Fprintln(w, s)
+	}
+	w.Flush()
+}
+
+// formatAge prints the duration since t in the short way kubectl does, e.g. 5s, 3m, 2h, 4d
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	d := time.Since(t)
+	switch {
+	case d < 0:
+		return "0s"
+	case d < 2*time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < 2*time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func FormatNodeDetail(out io.Writer, status *NodeStatus) {
+	fmt.Fprintf(out, "NODE: %s (%s)\n", status.Name, status.State)
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "VOLUME\tPV\tPVC\tPODS\tATTACHED\tIN USE\tPROBLEM")
+	for _, v := range status.Volumes {
+		s := fmt.Sprintf("%s\t%s\t%s\t%s\t%t\t%t\t%s", orNone(v.UniqueName), orNone(v.PV), orNone(v.PVC),
+			orNone(strings.Join(v.Pods, ",")), v.Attached, v.InUse, v.Problem)
+		fmt.Fprintln(w, s)
+	}
+	w.Flush()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+// FormatOrphans prints the orphan storage and the total capacity they hold
+func FormatOrphans(out io.Writer, report *OrphanReport) {
+	if len(report.Orphans) == 0 {
+		fmt.Fprintln(out, "No orphans found.")
+		return
+	}
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tCAPACITY\tREASON")
+	for _, o := range report.Orphans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", o.Kind, orNone(o.Namespace), o.Name, orNone(o.Capacity), o.ReasonString())
+	}
+	w.Flush()
+	fmt.Fprintf(out, "\nTOTAL: %d orphans, %s\n", len(report.Orphans), report.TotalCapacity)
+}
+
+// FormatWorkloadClaims prints the state of every pvc expected by the workload
+func FormatWorkloadClaims(out io.Writer, owner string, claims []*WorkloadClaim) {
+	fmt.Fprintf(out, "\nEXPECTED PVCS OF %s:\n", owner)
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSOURCE\tSTATE")
+	for _, claim := range claims {
+		source := "pod template"
+		if claim.Template != "" {
+			source = fmt.Sprintf("volumeClaimTemplate %s", claim.Template)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", claim.Name, source, claim.State)
+	}
+	w.Flush()
+}
+
+// statusRow is one row of the status printed in watch mode
+type statusRow struct {
+	object string
+	status string
+	detail string
+}
+
+// pvcStatusRows flattens the status of pvc into rows of pv, pods, nodes and phases
+func pvcStatusRows(status *PvcStatus) []statusRow {
+	rows := make([]statusRow, 0)
+	if status.PVStatus != nil {
+		rows = append(rows, statusRow{"pv/" + status.PVStatus.Name, status.PVStatus.Plugin, status.PVStatus.VolumeHandle})
+	}
+	for _, pod := range status.Pods {
+		rows = append(rows, statusRow{"pod/" + pod.Name, string(pod.MountStatus),
+			strings.TrimSpace(fmt.Sprintf("node %s %s", orNone(pod.Node), pod.MountReason))})
+	}
+	for _, node := range status.Nodes {
+		s := "not attached"
+		switch {
+		case node.Detaching:
+			s = "detaching"
+		case node.Attached:
+			s = "attached"
+		}
+		detail := fmt.Sprintf("desired %t", node.Desired)
+		if node.State != "" {
+			detail = fmt.Sprintf("%s, node %s", detail, node.State)
+		}
+		if node.AttachError != "" {
+			detail = fmt.Sprintf("%s, attach error: %s", detail, firstLine(node.AttachError))
+		}
+		if node.DetachError != "" {
+			detail = fmt.Sprintf("%s, detach error: %s", detail, firstLine(node.DetachError))
+		}
+		rows = append(rows, statusRow{"node/" + node.Name, s, detail})
+	}
+	for _, name := range allPhases {
+		phase := status.Phases[name]
+		rows = append(rows, statusRow{"phase/" + string(name), string(phase.Status), formatPhaseDetail(phase)})
+	}
+	return rows
+}
+
+// FormatPvcStatusChanges prints the rows of status which are added or changed since last with the time,
+// and the rows which are gone, all rows are printed if last is nil
+func FormatPvcStatusChanges(out io.Writer, last, status *PvcStatus, now time.Time) {
+	var lastRows []statusRow
+	if last != nil {
+		lastRows = pvcStatusRows(last)
+	}
+	rows := pvcStatusRows(status)
+	printRow := func(row statusRow) {
+		fmt.Fprintln(out, strings.TrimRight(fmt.Sprintf("%s   %-24s %-14s %s", now.Format("15:04:05"), row.object, row.status, row.detail), " "))
+	}
+
+	seen := make(map[string]statusRow)
+	for _, row := range lastRows {
+		seen[row.object] = row
+	}
+	for _, row := range rows {
+		if old, ok := seen[row.object]; !ok || old != row {
+			printRow(row)
+		}
+		delete(seen, row.object)
+	}
+	for _, row := range lastRows {
+		if _, ok := seen[row.object]; ok {
+			printRow(statusRow{object: row.object, status: "<gone>"})
+		}
+	}
+}
+
+// FormatPvcsUsage prints the filesystem usage of pvcs in the same way as df -h
+func FormatPvcsUsage(out io.Writer, usages []*PvcUsage, withNamespace bool) {
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	header := "NAME\tNODE\tUSED\tAVAILABLE\tCAPACITY\tUSE%\tIUSED\tIFREE\tINODES\tIUSE%\tPODS"
+	if withNamespace {
+		header = fmt.Sprintf("NAMESPACE\t%s", header)
+	}
+	fmt.Fprintln(w, header)
+	for _, u := range usages {
+		s := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s", u.Name, u.Node,
+			formatBytes(u.UsedBytes), formatBytes(u.AvailableBytes), formatBytes(u.CapacityBytes), formatPercent(u.UsedPercent()),
+			u.InodesUsed, u.InodesFree, u.Inodes, formatPercent(u.InodesUsedPercent()), strings.Join(u.Pods, ","))
+		if withNamespace {
+			s = fmt.Sprintf("%s\t%s", u.Namespace, s)
+		}
+		fmt.Fprintln(w, s)
+	}
 	w.Flush()
 }
+
+// formatBytes prints bytes in binary units, e.g. 1.5Gi
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d", bytes)
+	}
+	value := float64(bytes)
+	suffix := ""
+	for _, s := range []string{"Ki", "Mi", "Gi", "Ti", "Pi", "Ei"} {
+		if value < unit {
+			break
+		}
+		value /= unit
+		suffix = s
+	}
+	return fmt.Sprintf("%.1f%s", value, suffix)
+}
+
+func formatPercent(p float64) string {
+	if p < 0 {
+		return "<unknown>"
+	}
+	return fmt.Sprintf("%.0f%%", p)
+}
+
+// FormatSnapshots prints snapshots as table
+func FormatSnapshots(out io.Writer, snapshots []*Snapshot, withNamespace bool) {
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	header := "NAME\tPVC\tSNAPSHOTCLASS\tREADYTOUSE\tRESTORESIZE\tAGE\tERROR"
+	if withNamespace {
+		header = fmt.Sprintf("NAMESPACE\t%s", header)
+	}
+	fmt.Fprintln(w, header)
+	for _, s := range snapshots {
+		line := fmt.Sprintf("%s\t%s\t%s\t%t\t%s\t%s\t%s", s.Name, s.SourcePvc, orNone(s.SnapshotClass), s.ReadyToUse,
+			orNone(s.RestoreSize), formatAge(s.CreationTime), orNone(firstLine(s.Error)))
+		if withNamespace {
+			line = fmt.Sprintf("%s\t%s", s.Namespace, line)
+		}
+		fmt.Fprintln(w, line)
+	}
+	w.Flush()
+}
+
+// FormatMigratePlan prints the steps of migration, steps done by the previous run are marked as done
+func FormatMigratePlan(out io.Writer, plan *MigratePlan) {
+	state := func(done bool) string {
+		if done {
+			return "done"
+		}
+		return "pending"
+	}
+	scaleDone := plan.Copied && (!plan.Swap || plan.Swapped)
+
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "STEP\tSTATE\tACTION")
+	fmt.Fprintf(w, "1\t%s\tcreate pvc %s of storageclass %s with size %s\n", state(plan.TargetExists), plan.Target, plan.StorageClass, plan.Size.String())
+	if len(plan.Workloads) == 0 {
+		fmt.Fprintf(w, "2\t%s\tno workload to scale down\n", state(scaleDone))
+	}
+	for _, wl := range plan.Workloads {
+		fmt.Fprintf(w, "2\t%s\tscale down %s/%s from %d to 0\n", state(scaleDone), strings.ToLower(wl.Kind), wl.Name, plan.Replicas[workloadKey(wl)])
+	}
+	fmt.Fprintf(w, "3\t%s\tcopy data from pvc %s to pvc %s by %s in job %s, and delete the job\n", state(plan.Copied), plan.Source, plan.Target, plan.opt.Method, plan.Job)
+	if plan.Swap {
+		targetPV := "the pv"
+		if plan.TargetPV != "" {
+			targetPV = "pv " + plan.TargetPV
+		}
+		fmt.Fprintf(w, "4\t%s\tbind %s of pvc %s to a new pvc %s, and retain pv %s\n",
+			state(plan.Swapped), targetPV, plan.Target, plan.Source, orNone(plan.SourcePV))
+		for _, wl := range plan.Workloads {
+			fmt.Fprintf(w, "5\tpending\tscale up %s/%s to %d\n", strings.ToLower(wl.Kind), wl.Name, plan.Replicas[workloadKey(wl)])
+		}
+	}
+	w.Flush()
+
+	if len(plan.Blockers) > 0 {
+		fmt.Fprintf(out, "\nBLOCKED: pvc %s is used by pods which can not be scaled down, stop them before migrating:\n", plan.Source)
+		for _, b := range plan.Blockers {
+			fmt.Fprintf(out, "  %s\n", b)
+		}
+	}
+}
@@ -0,0 +1,781 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// the state of migration is saved in annotations, so that it can be resumed by running migrate again
+const (
+	// on workloads, replicas before they are scaled down
+	annMigrateReplicas = "kubectl-pvc/migrate-replicas"
+	// on target pvc, name of the source pvc
+	annMigrateSource = "kubectl-pvc/migrate-source"
+	// on pvs being swapped, namespace/name of the source pvc
+	annMigrateClaim = "kubectl-pvc/migrate-claim"
+	// on pvs being swapped, source or target
+	annMigrateRole = "kubectl-pvc/migrate-role"
+	// on target pv, reclaim policy before swapping
+	annMigrateReclaimPolicy = "kubectl-pvc/migrate-reclaim-policy"
+	// on target pvc, the data is copied and the copy job is deleted
+	annMigrateCopied = "kubectl-pvc/migrate-copied"
+	// on copy job, name and uid of the target pvc it copies data to
+	annMigrateTarget    = "kubectl-pvc/migrate-target"
+	annMigrateTargetUID = "kubectl-pvc/migrate-target-uid"
+
+	// label of copy job and its pods, the value is the name of source pvc
+	labelMigrate = "kubectl-pvc/migrate"
+	labelJobName = "job-name"
+
+	migrateRoleSource = "source"
+	migrateRoleTarget = "target"
+)
+
+const (
+	MigrateMethodTar   = "tar"
+	MigrateMethodRsync = "rsync"
+
+	// image of copy job for tar, an image with rsync should be given for rsync
+	DefaultMigrateImage = "busybox"
+)
+
+type MigrateOption struct {
+	StorageClass string
+	// name of target pvc, <pvc>-migrated by default
+	Target string
+	// tar or rsync
+	Method string
+	Image  string
+	// swap the pvs of source and target pvc, so that workloads keep using the same pvc name
+	Swap bool
+	// timeout of each waiting step, zero means waiting forever
+	Timeout time.Duration
+}
+
+// MigratePlan is the steps to migrate the data of pvc to another storageclass,
+// the steps done by the previous run are marked and skipped
+type MigratePlan struct {
+	Namespace    string
+	Source       string
+	Target       string
+	StorageClass string
+	Size         resource.Quantity
+	SourcePV     string
+	// empty if the target pvc is not bound yet
+	TargetPV string
+	// the target pvc is created by the previous run
+	TargetExists bool
+	// workloads using the source pvc, they are scaled down before copying
+	Workloads []*Workload
+	// replicas of every workload before migration
+	Replicas map[string]int32
+	// pods using the source pvc which can not be stopped by scaling down workloads
+	Blockers []string
+	Job      string
+	// the copy job succeeded in the previous run
+	Copied bool
+	// the copy is recorded on target pvc, or target pvc is deleted during swapping
+	copyRecorded bool
+	Swap         bool
+	// source and target are swapped in the previous run
+	Swapped bool
+
+	opt MigrateOption
+	// the target pvc, or the one to be created if it does not exist
+	target *corev1.PersistentVolumeClaim
+}
+
+func workloadKey(w *Workload) string {
+	return fmt.Sprintf("%s/%s", w.Kind, w.Name)
+}
+
+// PlanMigrate checks the state of migration of the pvc and plans the remaining steps
+func (p *PvcContext) PlanMigrate(namespace, pvcname string, opt MigrateOption) (*MigratePlan, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+	if namespace == "" {
+		namespace = p.namespace
+	}
+	if opt.Target == "" {
+		opt.Target = pvcname + "-migrated"
+	}
+	if opt.Method == "" {
+		opt.Method = MigrateMethodTar
+	}
+	if opt.Image == "" {
+		opt.Image = DefaultMigrateImage
+	}
+
+	plan := &MigratePlan{
+		Namespace:    namespace,
+		Source:       pvcname,
+		Target:       opt.Target,
+		StorageClass: opt.StorageClass,
+		Replicas:     make(map[string]int32),
+		Job:          "migrate-" + pvcname,
+		Swap:         opt.Swap,
+		opt:          opt,
+	}
+
+	cli := p.k8scli
+	if _, err := cli.StorageV1().StorageClasses().Get(opt.StorageClass, metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("get info about storageclass [%s] failed, err: %v", opt.StorageClass, err)
+	}
+
+	if err := p.planMigrateVolumes(plan); err != nil {
+		return nil, err
+	}
+	if err := p.planMigrateWorkloads(plan); err != nil {
+		return nil, err
+	}
+
+	if !plan.Copied && plan.TargetExists {
+		job, err := cli.BatchV1().Jobs(namespace).Get(plan.Job, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("get info about job [%s/%s] failed, err: %v", namespace, plan.Job, err)
+		}
+		if err != nil {
+			job = nil
+		}
+		plan.Copied = isCopyDone(plan.target, job)
+	}
+	return plan, nil
+}
+
+// isCopyDone returns true if the data is copied to the target pvc, either recorded on the pvc, or by a completed
+// copy job of the same pvc, a job of the deleted pvc with the same name does not count
+func isCopyDone(target *corev1.PersistentVolumeClaim, job *batchv1.Job) bool {
+	if target.Annotations[annMigrateCopied] == "true" {
+		return true
+	}
+	return job != nil && isCopyJobOf(job, target) && isJobFinished(job, batchv1.JobComplete)
+}
+
+func isCopyJobOf(job *batchv1.Job, target *corev1.PersistentVolumeClaim) bool {
+	return job.Annotations[annMigrateTarget] == target.Name && job.Annotations[annMigrateTargetUID] == string(target.UID)
+}
+
+// planMigrateVolumes finds the pvs of source and target, the source pvc may be deleted by the previous run during swapping
+func (p *PvcContext) planMigrateVolumes(plan *MigratePlan) error {
+	cli := p.k8scli
+	namespace := plan.Namespace
+
+	src, err := cli.CoreV1().PersistentVolumeClaims(namespace).Get(plan.Source, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", namespace, plan.Source, err)
+	}
+	if apierrors.IsNotFound(err) {
+		// the swapping is interrupted after the source pvc is deleted
+		notFound := err
+		if err := p.findSwappingPvs(plan); err != nil {
+			return err
+		}
+		if plan.TargetPV == "" {
+			return fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", namespace, plan.Source, notFound)
+		}
+		plan.Swap, plan.Copied, plan.copyRecorded, plan.TargetExists = true, true, true, true
+		return nil
+	}
+
+	if getVolumeMode(src.Spec.VolumeMode) == corev1.PersistentVolumeBlock {
+		return fmt.Errorf("pvc [%s/%s] is a block volume without file system, its data can not be copied by migrate", namespace, plan.Source)
+	}
+	if src.Spec.VolumeName == "" {
+		return fmt.Errorf("pvc [%s/%s] is not bound, there is no data to migrate", namespace, plan.Source)
+	}
+	pv, err := cli.CoreV1().PersistentVolumes().Get(src.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get info about pv [%s] failed, err: %v", src.Spec.VolumeName, err)
+	}
+	if pv.Annotations[annMigrateRole] == migrateRoleTarget {
+		// the source pvc is already bound to the migrated pv, only scaling up is left
+		plan.Swapped, plan.Copied, plan.copyRecorded, plan.TargetExists = true, true, true, true
+		return p.findSwappingPvs(plan)
+	}
+	if class, _ := getPvcStorageClassName(src); class == plan.StorageClass {
+		return fmt.Errorf("pvc [%s/%s] is already of storageclass %s", namespace, plan.Source, class)
+	}
+	plan.SourcePV = pv.Name
+	plan.Size = src.Spec.Resources.Requests[corev1.ResourceStorage]
+	if capacity, ok := src.Status.Capacity[corev1.ResourceStorage]; ok && capacity.Cmp(plan.Size) > 0 {
+		plan.Size = capacity
+	}
+
+	dst, err := cli.CoreV1().PersistentVolumeClaims(namespace).Get(plan.Target, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", namespace, plan.Target, err)
+	}
+	if err == nil {
+		if dst.Annotations[annMigrateSource] != plan.Source {
+			return fmt.Errorf("pvc [%s/%s] already exists and is not created by migration of pvc %s", namespace, plan.Target, plan.Source)
+		}
+		plan.TargetExists = true
+		plan.TargetPV = dst.Spec.VolumeName
+		plan.copyRecorded = dst.Annotations[annMigrateCopied] == "true"
+		plan.target = dst
+		return nil
+	}
+
+	// the swapping is interrupted after the target pvc is deleted
+	if err := p.findSwappingPvs(plan); err != nil {
+		return err
+	}
+	if plan.TargetPV != "" {
+		plan.Swap, plan.Copied, plan.copyRecorded, plan.TargetExists = true, true, true, true
+		return nil
+	}
+
+	target, err := newPvcFromSource(namespace, plan.Target, src, plan.Size, nil, PvcOverrides{StorageClass: plan.StorageClass})
+	if err != nil {
+		return err
+	}
+	target.Annotations = map[string]string{annMigrateSource: plan.Source}
+	plan.target = target
+	return nil
+}
+
+// findSwappingPvs finds the pvs annotated by the previous run during swapping
+func (p *PvcContext) findSwappingPvs(plan *MigratePlan) error {
+	pvList, err := p.k8scli.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list pvs from kubernetes apiserver failed, err %v", err)
+	}
+	for _, pv := range pvList.Items {
+		if pv.Annotations[annMigrateClaim] != plan.Namespace+"/"+plan.Source {
+			continue
+		}
+		switch pv.Annotations[annMigrateRole] {
+		case migrateRoleSource:
+			plan.SourcePV = pv.Name
+		case migrateRoleTarget:
+			plan.TargetPV = pv.Name
+			plan.Size = pv.Spec.Capacity[corev1.ResourceStorage]
+		}
+	}
+	return nil
+}
+
+// planMigrateWorkloads finds the workloads to scale down, and the pods which must be stopped by user
+func (p *PvcContext) planMigrateWorkloads(plan *MigratePlan) error {
+	workloads, err := p.listWorkloads(plan.Namespace)
+	if err != nil {
+		return err
+	}
+	for _, w := range workloads {
+		if w.Replicas == nil || w.Controlled || !w.References(plan.Source) {
+			continue
+		}
+		if replicas, ok := w.Annotations[annMigrateReplicas]; ok {
+			// scaled down by the previous run
+			var r int32
+			if _, err := fmt.Sscanf(replicas, "%d", &r); err != nil {
+				return fmt.Errorf("invalid annotation %s=%s of %s [%s/%s]", annMigrateReplicas, replicas, strings.ToLower(w.Kind), w.Namespace, w.Name)
+			}
+			plan.Replicas[workloadKey(w)] = r
+		} else if *w.Replicas > 0 {
+			plan.Replicas[workloadKey(w)] = *w.Replicas
+		} else {
+			continue
+		}
+		plan.Workloads = append(plan.Workloads, w)
+	}
+
+	if plan.Copied && (!plan.Swap || plan.Swapped) {
+		return nil
+	}
+	pods, err := p.podsUsingPvc(plan.Namespace, plan.Source)
+	if err != nil {
+		return err
+	}
+	for i := range pods {
+		kind, name, err := p.podController(&pods[i])
+		if err != nil {
+			return err
+		}
+		if _, ok := plan.Replicas[kind+"/"+name]; !ok {
+			plan.Blockers = append(plan.Blockers, fmt.Sprintf("pod %s (%s)", pods[i].Name, formatController(kind, name)))
+		}
+	}
+	return nil
+}
+
+func formatController(kind, name string) string {
+	if kind == "" {
+		return "no controller"
+	}
+	return fmt.Sprintf("controlled by %s/%s", kind, name)
+}
+
+// podsUsingPvc returns the pods which are not terminated and use the pvc, pods of copy job are excluded
+func (p *PvcContext) podsUsingPvc(namespace, pvcname string) ([]corev1.Pod, error) {
+	podList, err := p.k8scli.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about pods of namespace [%s] failed, err: %v", namespace, err)
+	}
+	pods := make([]corev1.Pod, 0)
+	for _, pod := range podList.Items {
+		if _, ok := pod.Labels[labelMigrate]; ok {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if used, _ := isPvcUsedByPod(pvcname, &pod); used {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// podController returns the top controller of pod, e.g. the Deployment of its ReplicaSet, kind is empty if there is none
+func (p *PvcContext) podController(pod *corev1.Pod) (kind, name string, err error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return "", "", nil
+	}
+	if ref.Kind != KindReplicaSet {
+		return ref.Kind, ref.Name, nil
+	}
+	rs, err := p.k8scli.AppsV1().ReplicaSets(pod.Namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("get info about replicaset [%s/%s] failed, err: %v", pod.Namespace, ref.Name, err)
+	}
+	if owner := metav1.GetControllerOf(rs); owner != nil {
+		return owner.Kind, owner.Name, nil
+	}
+	return KindReplicaSet, rs.Name, nil
+}
+
+// Migrate runs the remaining steps of the plan, and prints the progress to out
+func (p *PvcContext) Migrate(plan *MigratePlan, out io.Writer) error {
+	if len(plan.Blockers) > 0 {
+		return fmt.Errorf("pvc [%s/%s] is used by pods which can not be scaled down, stop them first: %s",
+			plan.Namespace, plan.Source, strings.Join(plan.Blockers, ", "))
+	}
+	cli := p.k8scli
+	namespace := plan.Namespace
+
+	if !plan.TargetExists {
+		target, err := cli.CoreV1().PersistentVolumeClaims(namespace).Create(plan.target)
+		if err != nil {
+			return fmt.Errorf("create pvc [%s/%s] failed, err: %v", namespace, plan.Target, err)
+		}
+		plan.target = target
+		fmt.Fprintf(out, "persistentvolumeclaim/%s created\n", plan.Target)
+	}
+
+	if !plan.Copied || (plan.Swap && !plan.Swapped) {
+		for _, w := range plan.Workloads {
+			replicas := fmt.Sprintf("%d", plan.Replicas[workloadKey(w)])
+			if err := p.scaleWorkload(w, 0, map[string]*string{annMigrateReplicas: &replicas}); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%s/%s scaled down from %s to 0\n", strings.ToLower(w.Kind), w.Name, replicas)
+		}
+		if err := p.waitPodsGone(namespace, plan.Source, plan.opt.Timeout); err != nil {
+			return err
+		}
+	}
+
+	if !plan.Copied {
+		if err := p.runCopyJob(plan, out); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "data of pvc %s is copied to pvc %s\n", plan.Source, plan.Target)
+	}
+	if !plan.copyRecorded {
+		if err := p.recordCopy(plan, out); err != nil {
+			return err
+		}
+	}
+
+	if !plan.Swap {
+		for _, w := range plan.Workloads {
+			fmt.Fprintf(out, "%s/%s is kept scaled down, update it to use pvc %s and scale it up to %d\n",
+				strings.ToLower(w.Kind), w.Name, plan.Target, plan.Replicas[workloadKey(w)])
+		}
+		return nil
+	}
+
+	if !plan.Swapped {
+		if err := p.swapPvs(plan, out); err != nil {
+			return err
+		}
+	}
+
+	for _, w := range plan.Workloads {
+		replicas := plan.Replicas[workloadKey(w)]
+		if err := p.scaleWorkload(w, replicas, map[string]*string{annMigrateReplicas: nil}); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s/%s scaled up to %d\n", strings.ToLower(w.Kind), w.Name, replicas)
+	}
+
+	removed := map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{
+		annMigrateClaim: nil, annMigrateRole: nil, annMigrateReclaimPolicy: nil,
+	}}}
+	if err := p.patchPv(plan.TargetPV, removed); err != nil {
+		return err
+	}
+	if plan.SourcePV != "" {
+		if err := p.patchPv(plan.SourcePV, removed); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "pv %s with the old data is retained, delete it after the new data is checked\n", plan.SourcePV)
+	}
+	return nil
+}
+
+// waitPodsGone waits until no pod is using the pvc
+func (p *PvcContext) waitPodsGone(namespace, pvcname string, timeout time.Duration) error {
+	var lastErr error
+	err := p.watchUntil(namespace, timeout, func() bool {
+		pods, err := p.podsUsingPvc(namespace, pvcname)
+		if err != nil {
+			lastErr = err
+			return true
+		}
+		return len(pods) == 0
+	})
+	if err == ErrTimeout {
+		return fmt.Errorf("pods using pvc [%s/%s] are not stopped in %v", namespace, pvcname, timeout)
+	}
+	if err != nil {
+		return err
+	}
+	return lastErr
+}
+
+func isJobFinished(job *batchv1.Job, t batchv1.JobConditionType) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == t && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func copyScript(method string) string {
+	if method == MigrateMethodRsync {
+		return `set -eo pipefail
+rsync -aHX --info=progress2 --no-inc-recursive /source/ /target/ | tr '\r' '\n'`
+	}
+	return `set -eo pipefail
+total=$(find /source -mindepth 1 | wc -l)
+cd /source
+tar cf - . | tar xvf - -C /target | awk -v total=$total '{n++; if (n % 1000 == 0) {printf "copied %d/%d files\n", n, total; fflush()}} END {printf "copied %d/%d files\n", n, total}'`
+}
+
+func newCopyJob(plan *MigratePlan) *batchv1.Job {
+	backoffLimit := int32(2)
+	labels := map[string]string{labelMigrate: plan.Source}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      plan.Job,
+			Namespace: plan.Namespace,
+			Labels:    labels,
+			Annotations: map[string]string{
+				annMigrateTarget:    plan.Target,
+				annMigrateTargetUID: string(plan.target.UID),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "copy",
+						Image:   plan.opt.Image,
+						Command: []string{"sh", "-c", copyScript(plan.opt.Method)},
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: "source", MountPath: "/source", ReadOnly: true},
+							{Name: "target", MountPath: "/target"},
+						},
+					}},
+					Volumes: []corev1.Volume{
+						{Name: "source", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: plan.Source,
+							ReadOnly:  true,
+						}}},
+						{Name: "target", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: plan.Target,
+						}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// runCopyJob creates the copy job, or recreates it if it failed in the previous run or it is for a deleted
+// target pvc with the same name, and streams the logs of its pods until it completes
+func (p *PvcContext) runCopyJob(plan *MigratePlan, out io.Writer) error {
+	cli := p.k8scli
+	namespace := plan.Namespace
+
+	job, err := cli.BatchV1().Jobs(namespace).Get(plan.Job, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("get info about job [%s/%s] failed, err: %v", namespace, plan.Job, err)
+	}
+	if err == nil && (isJobFinished(job, batchv1.JobFailed) || !isCopyJobOf(job, plan.target)) {
+		propagation := metav1.DeletePropagationForeground
+		if err := cli.BatchV1().Jobs(namespace).Delete(plan.Job, &metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+			return fmt.Errorf("delete job [%s/%s] failed, err: %v", namespace, plan.Job, err)
+		}
+		if err := p.waitJobGone(namespace, plan.Job, plan.opt.Timeout); err != nil {
+			return err
+		}
+		job = nil
+	} else if err != nil {
+		job = nil
+	}
+	if job == nil {
+		if _, err := cli.BatchV1().Jobs(namespace).Create(newCopyJob(plan)); err != nil {
+			return fmt.Errorf("create job [%s/%s] failed, err: %v", namespace, plan.Job, err)
+		}
+		fmt.Fprintf(out, "job/%s created\n", plan.Job)
+	}
+
+	streamed := make(map[string]bool)
+	for {
+		var (
+			pod     *corev1.Pod
+			done    bool
+			lastErr error
+		)
+		err := p.watchUntil(namespace, plan.opt.Timeout, func() bool {
+			job, err := cli.BatchV1().Jobs(namespace).Get(plan.Job, metav1.GetOptions{})
+			if err != nil {
+				lastErr = fmt.Errorf("get info about job [%s/%s] failed, err: %v", namespace, plan.Job, err)
+				return true
+			}
+			if isJobFinished(job, batchv1.JobComplete) {
+				done = true
+				return true
+			}
+			if isJobFinished(job, batchv1.JobFailed) {
+				lastErr = fmt.Errorf("job [%s/%s] failed, check the logs of its pods, and run migrate again to retry", namespace, plan.Job)
+				return true
+			}
+			pod, lastErr = p.nextCopyPod(namespace, plan.Job, streamed)
+			return pod != nil || lastErr != nil
+		})
+		if err == ErrTimeout {
+			return fmt.Errorf("job [%s/%s] does not complete in %v, run migrate again to follow it", namespace, plan.Job, plan.opt.Timeout)
+		}
+		if err != nil {
+			return err
+		}
+		if lastErr != nil || done {
+			return lastErr
+		}
+
+		streamed[pod.Name] = true
+		if err := p.streamPodLogs(pod, out); err != nil {
+			return err
+		}
+	}
+}
+
+// nextCopyPod returns the pod of copy job which is started and not streamed yet
+func (p *PvcContext) nextCopyPod(namespace, job string, streamed map[string]bool) (*corev1.Pod, error) {
+	selector := fmt.Sprintf("%s=%s", labelJobName, job)
+	podList, err := p.k8scli.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("get info about pods of job [%s/%s] failed, err: %v", namespace, job, err)
+	}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if streamed[pod.Name] {
+			continue
+		}
+		switch pod.Status.Phase {
+		case corev1.PodRunning, corev1.PodSucceeded, corev1.PodFailed:
+			return pod, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *PvcContext) streamPodLogs(pod *corev1.Pod, out io.Writer) error {
+	stream, err := p.k8scli.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Follow: true}).Stream()
+	if err != nil {
+		return fmt.Errorf("get logs of pod [%s/%s] failed, err: %v", pod.Namespace, pod.Name, err)
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			fmt.Fprintf(out, "[%s] %s\n", pod.Name, line)
+		}
+	}
+	return nil
+}
+
+// recordCopy records the copy on target pvc, and then deletes the copy job together with its pods
+func (p *PvcContext) recordCopy(plan *MigratePlan, out io.Writer) error {
+	cli := p.k8scli
+	namespace := plan.Namespace
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, annMigrateCopied)
+	if _, err := cli.CoreV1().PersistentVolumeClaims(namespace).Patch(plan.Target, types.MergePatchType, []byte(patch)); err != nil {
+		return fmt.Errorf("patch pvc [%s/%s] failed, err: %v", namespace, plan.Target, err)
+	}
+	plan.copyRecorded = true
+
+	propagation := metav1.DeletePropagationBackground
+	err := cli.BatchV1().Jobs(namespace).Delete(plan.Job, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete job [%s/%s] failed, err: %v", namespace, plan.Job, err)
+	}
+	if err == nil {
+		fmt.Fprintf(out, "job/%s deleted\n", plan.Job)
+	}
+	return nil
+}
+
+func (p *PvcContext) waitJobGone(namespace, job string, timeout time.Duration) error {
+	err := p.watchUntil(namespace, timeout, func() bool {
+		_, err := p.k8scli.BatchV1().Jobs(namespace).Get(job, metav1.GetOptions{})
+		return apierrors.IsNotFound(err)
+	})
+	if err == ErrTimeout {
+		return fmt.Errorf("job [%s/%s] is not deleted in %v", namespace, job, timeout)
+	}
+	return err
+}
+
+// swapPvs binds the pv of target pvc to a new pvc with the name of source pvc, both pvs are retained
+// during swapping, and the pv of source pvc is kept after swapping in case the data should be checked
+func (p *PvcContext) swapPvs(plan *MigratePlan, out io.Writer) error {
+	cli := p.k8scli
+	namespace := plan.Namespace
+	claim := namespace + "/" + plan.Source
+
+	if plan.TargetPV == "" {
+		dst, err := cli.CoreV1().PersistentVolumeClaims(namespace).Get(plan.Target, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", namespace, plan.Target, err)
+		}
+		if dst.Spec.VolumeName == "" {
+			return fmt.Errorf("pvc [%s/%s] is not bound, it can not be swapped", namespace, plan.Target)
+		}
+		plan.TargetPV = dst.Spec.VolumeName
+	}
+	target, err := cli.CoreV1().PersistentVolumes().Get(plan.TargetPV, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get info about pv [%s] failed, err: %v", plan.TargetPV, err)
+	}
+
+	// retain both pvs, so that they are not deleted together with the pvcs
+	reclaimPolicy := string(target.Spec.PersistentVolumeReclaimPolicy)
+	if policy, ok := target.Annotations[annMigrateReclaimPolicy]; ok {
+		reclaimPolicy = policy
+	}
+	if err := p.patchPv(plan.TargetPV, map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": map[string]string{
+			annMigrateClaim: claim, annMigrateRole: migrateRoleTarget, annMigrateReclaimPolicy: reclaimPolicy,
+		}},
+		"spec": map[string]interface{}{"persistentVolumeReclaimPolicy": corev1.PersistentVolumeReclaimRetain},
+	}); err != nil {
+		return err
+	}
+	if plan.SourcePV != "" {
+		if err := p.patchPv(plan.SourcePV, map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": map[string]string{
+				annMigrateClaim: claim, annMigrateRole: migrateRoleSource,
+			}},
+			"spec": map[string]interface{}{"persistentVolumeReclaimPolicy": corev1.PersistentVolumeReclaimRetain},
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range []string{plan.Target, plan.Source} {
+		if err := p.deletePvc(namespace, name, plan.opt.Timeout); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "persistentvolumeclaim/%s deleted\n", name)
+	}
+
+	// pre-bind the target pv to the new pvc with the name of source
+	if err := p.patchPv(plan.TargetPV, map[string]interface{}{
+		"spec": map[string]interface{}{"claimRef": map[string]interface{}{
+			"namespace": namespace, "name": plan.Source, "uid": nil, "resourceVersion": nil,
+		}},
+	}); err != nil {
+		return err
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: plan.Source, Namespace: namespace},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: target.Spec.AccessModes,
+			VolumeMode:  target.Spec.VolumeMode,
+			VolumeName:  plan.TargetPV,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: target.Spec.Capacity[corev1.ResourceStorage]},
+			},
+		},
+	}
+	class := getPvStorageClassName(target)
+	pvc.Spec.StorageClassName = &class
+	if _, err := cli.CoreV1().PersistentVolumeClaims(namespace).Create(pvc); err != nil {
+		return fmt.Errorf("create pvc [%s/%s] failed, err: %v", namespace, plan.Source, err)
+	}
+	fmt.Fprintf(out, "persistentvolumeclaim/%s created with pv %s\n", plan.Source, plan.TargetPV)
+
+	if err := p.WaitPvcPhase(namespace, plan.Source, PvcBind, plan.opt.Timeout, func(PvcPhaseName, PvcPhaseStatus, string) {}); err != nil {
+		return err
+	}
+
+	// the annotations are kept until workloads are scaled up, so that the swapping is not done again
+	return p.patchPv(plan.TargetPV, map[string]interface{}{
+		"spec": map[string]interface{}{"persistentVolumeReclaimPolicy": reclaimPolicy},
+	})
+}
+
+func (p *PvcContext) patchPv(name string, patch map[string]interface{}) error {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	if _, err := p.k8scli.CoreV1().PersistentVolumes().Patch(name, types.MergePatchType, data); err != nil {
+		return fmt.Errorf("patch pv [%s] failed, err: %v", name, err)
+	}
+	return nil
+}
+
+// deletePvc deletes the pvc and waits until it is gone, it does nothing if the pvc does not exist
+func (p *PvcContext) deletePvc(namespace, name string, timeout time.Duration) error {
+	err := p.k8scli.CoreV1().PersistentVolumeClaims(namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("delete pvc [%s/%s] failed, err: %v", namespace, name, err)
+	}
+	err = p.watchUntil(namespace, timeout, func() bool {
+		_, err := p.k8scli.CoreV1().PersistentVolumeClaims(namespace).Get(name, metav1.GetOptions{})
+		return apierrors.IsNotFound(err)
+	})
+	if err == ErrTimeout {
+		return fmt.Errorf("pvc [%s/%s] is not deleted in %v, check whether it is still used by some pod", namespace, name, timeout)
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestIsCopyDone(t *testing.T) {
+	newTarget := func(uid string, annotations map[string]string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "team-a",
+			Name:        "data-migrated",
+			UID:         types.UID(uid),
+			Annotations: annotations,
+		}}
+	}
+	newJob := func(target, uid string, condition batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "team-a",
+			Name:        "migrate-data",
+			Annotations: map[string]string{annMigrateTarget: target, annMigrateTargetUID: uid},
+		}}
+		if condition != "" {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+		}
+		return job
+	}
+
+	tests := []struct {
+		name   string
+		target *corev1.PersistentVolumeClaim
+		job    *batchv1.Job
+		copied bool
+	}{
+		{
+			name:   "not started",
+			target: newTarget("uid-1", nil),
+		},
+		{
+			name:   "recorded on target pvc after the job is deleted",
+			target: newTarget("uid-1", map[string]string{annMigrateCopied: "true"}),
+			copied: true,
+		},
+		{
+			name:   "job completed before the copy is recorded",
+			target: newTarget("uid-1", nil),
+			job:    newJob("data-migrated", "uid-1", batchv1.JobComplete),
+			copied: true,
+		},
+		{
+			name:   "job is running",
+			target: newTarget("uid-1", nil),
+			job:    newJob("data-migrated", "uid-1", ""),
+		},
+		{
+			name:   "job failed",
+			target: newTarget("uid-1", nil),
+			job:    newJob("data-migrated", "uid-1", batchv1.JobFailed),
+		},
+		{
+			name:   "job completed for the deleted target pvc with the same name",
+			target: newTarget("uid-2", nil),
+			job:    newJob("data-migrated", "uid-1", batchv1.JobComplete),
+		},
+		{
+			name:   "job completed for another target pvc",
+			target: newTarget("uid-1", nil),
+			job:    newJob("data-copy", "uid-1", batchv1.JobComplete),
+		},
+		{
+			name:   "job completed without target",
+			target: newTarget("uid-1", nil),
+			job:    &batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}}},
+		},
+	}
+	for _, test := range tests {
+		if copied := isCopyDone(test.target, test.job); copied != test.copied {
+			t.Errorf("%s: expected copied %v, got %v", test.name, test.copied, copied)
+		}
+	}
+}
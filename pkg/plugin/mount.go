@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+type PodMountStatus string
+
+const (
+	PodMountMounted    PodMountStatus = "mounted"
+	PodMountWaiting    PodMountStatus = "waiting"
+	PodMountFailed     PodMountStatus = "failed"
+	PodMountTerminated PodMountStatus = "pod-terminated"
+)
+
+// failure events older than this are considered to be recovered by the retry of kubelet,
+// kubelet retries mounting volume at most every about 2 minutes
+const recentEventWindow = 5 * time.Minute
+
+// reasons of container waiting while kubelet is still preparing the pod, including mounting volumes
+var podPreparingReasons = map[string]bool{
+	"ContainerCreating": true,
+	"PodInitializing":   true,
+}
+
+// deducePodMount tells whether the volumes of pod are mounted,
+// events are the events of this pod sorted from the newest to the oldest
+func deducePodMount(pod *corev1.Pod, events []*Event) (PodMountStatus, string) {
+	switch {
+	case pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed:
+		return PodMountTerminated, fmt.Sprintf("pod is %s", pod.Status.Phase)
+	case pod.DeletionTimestamp != nil:
+		return PodMountTerminated, "pod is terminating"
+	}
+
+	if cond := getPodCondition(pod, corev1.PodScheduled); cond != nil && cond.Status == corev1.ConditionFalse {
+		return PodMountWaiting, fmt.Sprintf("pod is not scheduled: %s", cond.Message)
+	}
+	if pod.Spec.NodeName == "" {
+		return PodMountWaiting, "pod is not scheduled"
+	}
+
+	// volumes are mounted before kubelet starts any container of the pod
+	if cond := getPodCondition(pod, corev1.ContainersReady); cond != nil && cond.Status == corev1.ConditionTrue {
+		return PodMountMounted, ""
+	}
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if cs.State.Running != nil || cs.State.Terminated != nil || cs.LastTerminationState.Terminated != nil {
+			return PodMountMounted, ""
+		}
+	}
+
+	for _, e := range events {
+		if e.Type != corev1.EventTypeWarning || time.Since(e.LastTimestamp) > recentEventWindow {
+			continue
+		}
+		if e.Reason == eventReasonFailedMount || e.Reason == eventReasonFailedMapVolume || e.Reason == eventReasonFailedAttachVolume {
+			return PodMountFailed, fmt.Sprintf("%s: %s", e.Reason, firstLine(e.Message))
+		}
+	}
+
+	for _, cs := range statuses {
+		if cs.State.Waiting == nil || podPreparingReasons[cs.State.Waiting.Reason] {
+			continue
+		}
+		// kubelet creates containers only after all volumes are mounted,
+		// so errors like CreateContainerConfigError come after mounting
+		return PodMountMounted, fmt.Sprintf("container %s is waiting: %s", cs.Name, cs.State.Waiting.Reason)
+	}
+
+	if cond := getPodCondition(pod, corev1.PodInitialized); cond != nil && cond.Status == corev1.ConditionTrue && len(pod.Spec.InitContainers) > 0 {
+		return PodMountMounted, ""
+	}
+
+	return PodMountWaiting, "kubelet is still preparing pod, volumes may be attaching or mounting"
+}
+
+func getPodCondition(pod *corev1.Pod, t corev1.PodConditionType) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == t {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
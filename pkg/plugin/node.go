@@ -0,0 +1,167 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+type NodeStatus struct {
+	Name string `json:"name"`
+	// Ready, NotReady or Unknown
+	State   string        `json:"state"`
+	Volumes []*NodeVolume `json:"volumes"`
+}
+
+// NodeVolume is one volume attached to or used on the node, traced back to its pv, pvc and pods
+type NodeVolume struct {
+	// the unique name reported in volumesAttached and volumesInUse of Node
+	UniqueName       string   `json:"uniqueName"`
+	PV               string   `json:"pv,omitempty"`
+	PVC              string   `json:"pvc,omitempty"`
+	Pods             []string `json:"pods"`
+	VolumeAttachment string   `json:"volumeAttachment,omitempty"`
+	// reported in volumesAttached of Node or by VolumeAttachment
+	Attached bool `json:"attached"`
+	// reported in volumesInUse of Node by kubelet
+	InUse   bool   `json:"inUse"`
+	Problem string `json:"problem,omitempty"`
+}
+
+// GetNodeDetail maps every volume attached to or used on the node back to pv, pvc and pods
+func (p *PvcContext) GetNodeDetail(nodename string) (*NodeStatus, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+
+	cli := p.k8scli
+
+	node, err := cli.CoreV1().Nodes().Get(nodename, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about node [%s] failed, err: %v", nodename, err)
+	}
+
+	status := &NodeStatus{
+		Name:    node.Name,
+		State:   getNodeState(node),
+		Volumes: []*NodeVolume{},
+	}
+
+	pvList, err := cli.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list pvs from kubernetes apiserver failed, err %v", err)
+	}
+	pvsByName := make(map[string]*corev1.PersistentVolume)
+	pvsByUniqueName := make(map[string]*corev1.PersistentVolume)
+	for i := range pvList.Items {
+		pv := &pvList.Items[i]
+		pvsByName[pv.Name] = pv
+		if _, name, err := getAttachedVolumeName(pv); err == nil && name != "" {
+			pvsByUniqueName[name] = pv
+		}
+	}
+
+	volumes := make(map[string]*NodeVolume)
+	getVolume := func(uniqueName string) *NodeVolume {
+		if v, ok := volumes[uniqueName]; ok {
+			return v
+		}
+		v := &NodeVolume{UniqueName: uniqueName, Pods: []string{}}
+		if pv, ok := pvsByUniqueName[uniqueName]; ok {
+			v.PV = pv.Name
+		}
+		volumes[uniqueName] = v
+		return v
+	}
+
+	for _, vol := range node.Status.VolumesAttached {
+		getVolume(string(vol.Name)).Attached = true
+	}
+	for _, vol := range node.Status.VolumesInUse {
+		getVolume(string(vol)).InUse = true
+	}
+
+	// VolumeAttachment is not served by old clusters
+	vas := make([]storagev1.VolumeAttachment, 0)
+	vaList, err := cli.StorageV1().VolumeAttachments().List(metav1.ListOptions{})
+	if err == nil {
+		vas = vaList.Items
+	} else if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("get info about volumeattachments failed, err: %v", err)
+	}
+	for i := range vas {
+		va := &vas[i]
+		if va.Spec.NodeName != nodename || va.Spec.Source.PersistentVolumeName == nil {
+			continue
+		}
+		pv, ok := pvsByName[*va.Spec.Source.PersistentVolumeName]
+		if !ok {
+			// the unique name is unknown without pv
+			volumes["volumeattachment/"+va.Name] = &NodeVolume{
+				Pods:             []string{},
+				VolumeAttachment: va.Name,
+				Attached:         va.Status.Attached,
+				Problem:          fmt.Sprintf("pv %s of volumeattachment is not found", *va.Spec.Source.PersistentVolumeName),
+			}
+			continue
+		}
+		_, uniqueName, err := getAttachedVolumeName(pv)
+		if err != nil {
+			continue
+		}
+		v := getVolume(uniqueName)
+		v.VolumeAttachment = va.Name
+		v.Attached = v.Attached || va.Status.Attached
+	}
+
+	selector := fields.OneTermEqualSelector("spec.nodeName", nodename).String()
+	podList, err := cli.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("get info about pods on node [%s] failed, err: %v", nodename, err)
+	}
+
+	for _, v := range volumes {
+		pv, ok := pvsByName[v.PV]
+		if ok && pv.Spec.ClaimRef != nil {
+			ref := pv.Spec.ClaimRef
+			v.PVC = fmt.Sprintf("%s/%s", ref.Namespace, ref.Name)
+			for i := range podList.Items {
+				pod := &podList.Items[i]
+				if pod.Namespace != ref.Namespace || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+					continue
+				}
+				if used, _ := isPvcUsedByPod(ref.Name, pod); used {
+					v.Pods = append(v.Pods, pod.Name)
+				}
+			}
+		}
+		if v.Problem == "" {
+			v.Problem = deduceNodeVolumeProblem(v)
+		}
+		status.Volumes = append(status.Volumes, v)
+	}
+
+	sort.Slice(status.Volumes, func(i, j int) bool {
+		return status.Volumes[i].UniqueName < status.Volumes[j].UniqueName
+	})
+	return status, nil
+}
+
+func deduceNodeVolumeProblem(v *NodeVolume) string {
+	switch {
+	case v.InUse && !v.Attached:
+		return "in use by kubelet but not attached"
+	case v.Attached && v.PV == "":
+		return "attached but no pv is found for it"
+	case v.Attached && len(v.Pods) == 0 && v.InUse:
+		return "attached and still in use by kubelet, but no pod on this node is using it"
+	case v.Attached && len(v.Pods) == 0:
+		return "attached but unused, no pod on this node is using it"
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// clusterObjects caches the objects used to deduce the status of pvcs. Lists are done at most once, and when
+// the status of many pvcs is deduced, pvs, nodes, storageclasses and events are also looked up in the lists
+// instead of getting them one by one.
+type clusterObjects struct {
+	// look up pvs, nodes, storageclasses and events in the lists
+	prefetch bool
+
+	pvs        []corev1.PersistentVolume
+	pvsByName  map[string]*corev1.PersistentVolume
+	nodes      []corev1.Node
+	nodeByName map[string]*corev1.Node
+	vas        []storagev1.VolumeAttachment
+	// false if VolumeAttachment is not served by old clusters
+	vasServed bool
+	vasListed bool
+	// attachRequired of CSIDriver by the name of driver
+	attachRequired map[string]bool
+	scs            []storagev1.StorageClass
+	scByName       map[string]*storagev1.StorageClass
+	// phase events by the uid of involved object, and the namespaces whose events are listed
+	eventsByUID  map[types.UID][]*Event
+	eventsListed map[string]bool
+}
+
+func newClusterObjects(prefetch bool) *clusterObjects {
+	return &clusterObjects{
+		prefetch:       prefetch,
+		attachRequired: make(map[string]bool),
+		eventsByUID:    make(map[types.UID][]*Event),
+		eventsListed:   make(map[string]bool),
+	}
+}
+
+func (p *PvcContext) listPvs(objs *clusterObjects) ([]corev1.PersistentVolume, error) {
+	if objs.pvsByName != nil {
+		return objs.pvs, nil
+	}
+	pvList, err := p.k8scli.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list pvs from kubernetes apiserver failed, err %v", err)
+	}
+	objs.pvs = pvList.Items
+	objs.pvsByName = make(map[string]*corev1.PersistentVolume)
+	for i := range objs.pvs {
+		objs.pvsByName[objs.pvs[i].Name] = &objs.pvs[i]
+	}
+	return objs.pvs, nil
+}
+
+// getPv returns the pv, the error is NotFound if it does not exist
+func (p *PvcContext) getPv(objs *clusterObjects, name string) (*corev1.PersistentVolume, error) {
+	if !objs.prefetch {
+		return p.k8scli.CoreV1().PersistentVolumes().Get(name, metav1.GetOptions{})
+	}
+	if _, err := p.listPvs(objs); err != nil {
+		return nil, err
+	}
+	if pv, ok := objs.pvsByName[name]; ok {
+		return pv, nil
+	}
+	return nil, apierrors.NewNotFound(corev1.Resource("persistentvolumes"), name)
+}
+
+func (p *PvcContext) listNodes(objs *clusterObjects) ([]corev1.Node, error) {
+	if objs.nodeByName != nil {
+		return objs.nodes, nil
+	}
+	nodeList, err := p.k8scli.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about nodes failed, err: %v", err)
+	}
+	objs.nodes = nodeList.Items
+	objs.nodeByName = make(map[string]*corev1.Node)
+	for i := range objs.nodes {
+		objs.nodeByName[objs.nodes[i].Name] = &objs.nodes[i]
+	}
+	return objs.nodes, nil
+}
+
+// getNode returns the node, the error is NotFound if it does not exist
+func (p *PvcContext) getNode(objs *clusterObjects, name string) (*corev1.Node, error) {
+	if !objs.prefetch {
+		return p.k8scli.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+	}
+	if _, err := p.listNodes(objs); err != nil {
+		return nil, err
+	}
+	if node, ok := objs.nodeByName[name]; ok {
+		return node, nil
+	}
+	return nil, apierrors.NewNotFound(corev1.Resource("nodes"), name)
+}
+
+// listVolumeAttachments returns false if VolumeAttachment is not served by apiserver
+func (p *PvcContext) listVolumeAttachments(objs *clusterObjects) ([]storagev1.VolumeAttachment, bool, error) {
+	if objs.vasListed {
+		return objs.vas, objs.vasServed, nil
+	}
+	vaList, err := p.k8scli.StorageV1().VolumeAttachments().List(metav1.ListOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, false, fmt.Errorf("get info about volumeattachments failed, err: %v", err)
+	}
+	objs.vasListed = true
+	if err == nil {
+		objs.vas = vaList.Items
+		objs.vasServed = true
+	}
+	return objs.vas, objs.vasServed, nil
+}
+
+func (p *PvcContext) listStorageClasses(objs *clusterObjects) ([]storagev1.StorageClass, error) {
+	if objs.scByName != nil {
+		return objs.scs, nil
+	}
+	scList, err := p.k8scli.StorageV1().StorageClasses().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list storageclasses from kubernetes apiserver failed, err %v", err)
+	}
+	objs.scs = scList.Items
+	objs.scByName = make(map[string]*storagev1.StorageClass)
+	for i := range objs.scs {
+		objs.scByName[objs.scs[i].Name] = &objs.scs[i]
+	}
+	return objs.scs, nil
+}
+
+// getStorageClass returns the storageclass, the error is NotFound if it does not exist
+func (p *PvcContext) getStorageClass(objs *clusterObjects, name string) (*storagev1.StorageClass, error) {
+	if !objs.prefetch {
+		return p.k8scli.StorageV1().StorageClasses().Get(name, metav1.GetOptions{})
+	}
+	if _, err := p.listStorageClasses(objs); err != nil {
+		return nil, err
+	}
+	if sc, ok := objs.scByName[name]; ok {
+		return sc, nil
+	}
+	return nil, apierrors.NewNotFound(storagev1.Resource("storageclasses"), name)
+}
+
+// listEvents lists the phase events of the namespace once and indexes them by the uid of involved object,
+// namespace should be empty for all namespaces
+func (p *PvcContext) listEvents(objs *clusterObjects, namespace string) error {
+	if objs.eventsListed[metav1.NamespaceAll] || objs.eventsListed[namespace] {
+		return nil
+	}
+	eventList, err := p.k8scli.CoreV1().Events(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list events from kubernetes apiserver failed, err %v", err)
+	}
+	objs.eventsListed[namespace] = true
+	for i := range eventList.Items {
+		e := &eventList.Items[i]
+		if phase, ok := eventReasonPhases[e.Reason]; ok {
+			objs.eventsByUID[e.InvolvedObject.UID] = append(objs.eventsByUID[e.InvolvedObject.UID], NewEvent(e, phase))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestListPhaseEventsPrefetched(t *testing.T) {
+	pvcEvent := &Event{Phase: PvcProvision, Reason: eventReasonProvisioningFailed}
+	pvEvent := &Event{Phase: PvcResize, Reason: eventReasonVolumeResizeFailed}
+
+	tests := []struct {
+		name      string
+		listed    []string
+		namespace string
+		uid       types.UID
+		events    []*Event
+	}{
+		{
+			name:      "namespaced object",
+			listed:    []string{"team-a"},
+			namespace: "team-a",
+			uid:       "uid-pvc",
+			events:    []*Event{pvcEvent},
+		},
+		{
+			name:      "cluster scoped object is recorded in default namespace",
+			listed:    []string{"team-a", metav1.NamespaceDefault},
+			namespace: metav1.NamespaceAll,
+			uid:       "uid-pv",
+			events:    []*Event{pvEvent},
+		},
+		{
+			name:      "events of all namespaces",
+			listed:    []string{metav1.NamespaceAll},
+			namespace: "team-b",
+			uid:       "uid-pvc",
+			events:    []*Event{pvcEvent},
+		},
+		{
+			name:      "no event",
+			listed:    []string{"team-a"},
+			namespace: "team-a",
+			uid:       "uid-pod",
+		},
+	}
+	for _, test := range tests {
+		objs := newClusterObjects(true)
+		for _, ns := range test.listed {
+			objs.eventsListed[ns] = true
+		}
+		objs.eventsByUID["uid-pvc"] = []*Event{pvcEvent}
+		objs.eventsByUID["uid-pv"] = []*Event{pvEvent}
+
+		// apiserver is not called since the events are listed
+		events, err := (&PvcContext{}).listPhaseEvents(objs, test.namespace, test.uid)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if len(events) != len(test.events) {
+			t.Errorf("%s: expected %d events, got %d", test.name, len(test.events), len(events))
+			continue
+		}
+		for i := range events {
+			if events[i] != test.events[i] {
+				t.Errorf("%s: expected event %s, got %s", test.name, test.events[i].Reason, events[i].Reason)
+			}
+		}
+		// the index is not changed by the caller
+		events = append(events, &Event{})
+		if len(objs.eventsByUID[test.uid]) != len(test.events) {
+			t.Errorf("%s: events in index are changed", test.name)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	KindPersistentVolumeClaim = "PersistentVolumeClaim"
+	KindPersistentVolume      = "PersistentVolume"
+	KindVolumeAttachment      = "VolumeAttachment"
+)
+
+// Orphan is the storage which is not used by anyone
+type Orphan struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// empty if the capacity is unknown
+	Capacity string   `json:"capacity,omitempty"`
+	Reasons  []string `json:"reasons"`
+
+	capacity resource.Quantity
+}
+
+type OrphanReport struct {
+	Orphans       []*Orphan `json:"orphans"`
+	TotalCapacity string    `json:"totalCapacity"`
+}
+
+func newOrphan(kind, namespace, name string, capacity *resource.Quantity, reasons ...string) *Orphan {
+	o := &Orphan{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Reasons:   reasons,
+	}
+	if capacity != nil {
+		o.capacity = capacity.DeepCopy()
+		o.Capacity = capacity.String()
+	}
+	return o
+}
+
+// FindOrphans finds the pvcs not referenced by any pod or workload, and the pvs and volumeattachments
+// which are left over. pvcs are only searched in the namespace of context unless allNamespaces is true,
+// pvs and volumeattachments are cluster scoped so they are always searched.
+func (p *PvcContext) FindOrphans(allNamespaces bool) (*OrphanReport, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+
+	cli := p.k8scli
+	orphans := make([]*Orphan, 0)
+
+	pvcOrphans, err := p.findOrphanPvcs(allNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	orphans = append(orphans, pvcOrphans...)
+
+	pvList, err := cli.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list pvs from kubernetes apiserver failed, err %v", err)
+	}
+	pvcList, err := cli.CoreV1().PersistentVolumeClaims(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list pvcs from kubernetes apiserver failed, err %v", err)
+	}
+	pvcUIDs := make(map[string]types.UID)
+	for _, pvc := range pvcList.Items {
+		pvcUIDs[pvc.Namespace+"/"+pvc.Name] = pvc.UID
+	}
+	pvs := make(map[string]*corev1.PersistentVolume)
+	for i := range pvList.Items {
+		pv := &pvList.Items[i]
+		pvs[pv.Name] = pv
+		if o := checkOrphanPv(pv, pvcUIDs); o != nil {
+			orphans = append(orphans, o)
+		}
+	}
+
+	vaOrphans, err := p.findOrphanVolumeAttachments(pvs)
+	if err != nil {
+		return nil, err
+	}
+	orphans = append(orphans, vaOrphans...)
+
+	total := resource.NewQuantity(0, resource.BinarySI)
+	for _, o := range orphans {
+		total.Add(o.capacity)
+	}
+
+	return &OrphanReport{
+		Orphans:       orphans,
+		TotalCapacity: total.String(),
+	}, nil
+}
+
+func (p *PvcContext) findOrphanPvcs(allNamespaces bool) ([]*Orphan, error) {
+	namespace := p.namespace
+	if allNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+
+	pvcs, err := p.ListPvcs(ListPvcsOption{AllNamespaces: allNamespaces})
+	if err != nil {
+		return nil, err
+	}
+
+	podList, err := p.k8scli.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about pods of namespace [%s] failed, err: %v", namespace, err)
+	}
+
+	workloads, err := p.listWorkloads(namespace)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool)
+	for _, w := range workloads {
+		for _, claim := range w.AllClaims() {
+			referenced[w.Namespace+"/"+claim] = true
+		}
+	}
+
+	orphans := make([]*Orphan, 0)
+	for i := range pvcs {
+		if o := checkOrphanPvc(&pvcs[i], podList.Items, referenced); o != nil {
+			orphans = append(orphans, o)
+		}
+	}
+	return orphans, nil
+}
+
+// checkOrphanPvc returns nil if the pvc is used by any pod or referenced by any workload,
+// referenced is keyed by <namespace>/<name> of pvcs
+func checkOrphanPvc(pvc *corev1.PersistentVolumeClaim, pods []corev1.Pod, referenced map[string]bool) *Orphan {
+	if referenced[pvc.Namespace+"/"+pvc.Name] {
+		return nil
+	}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Namespace != pvc.Namespace {
+			continue
+		}
+		if used, _ := isPvcUsedByPod(pvc.Name, pod); used {
+			return nil
+		}
+	}
+	return newOrphan(KindPersistentVolumeClaim, pvc.Namespace, pvc.Name, getPvcCapacity(pvc), "not used by any pod or workload")
+}
+
+// checkOrphanPv returns nil if the pv is bound to an existing pvc
+func checkOrphanPv(pv *corev1.PersistentVolume, pvcUIDs map[string]types.UID) *Orphan {
+	reasons := make([]string, 0)
+	switch pv.Status.Phase {
+	case corev1.VolumeReleased, corev1.VolumeAvailable, corev1.VolumeFailed:
+		reasons = append(reasons, fmt.Sprintf("pv is %s", pv.Status.Phase))
+	}
+
+	if ref := pv.Spec.ClaimRef; ref != nil {
+		uid, ok := pvcUIDs[ref.Namespace+"/"+ref.Name]
+		switch {
+		case !ok:
+			reasons = append(reasons, fmt.Sprintf("claimRef points at missing pvc %s/%s", ref.Namespace, ref.Name))
+		case ref.UID != "" && ref.UID != uid:
+			reasons = append(reasons, fmt.Sprintf("claimRef uid %s does not match pvc %s/%s", ref.UID, ref.Namespace, ref.Name))
+		}
+	}
+
+	if len(reasons) == 0 {
+		return nil
+	}
+	capacity := pv.Spec.Capacity[corev1.ResourceStorage]
+	return newOrphan(KindPersistentVolume, "", pv.Name, &capacity, reasons...)
+}
+
+func (p *PvcContext) findOrphanVolumeAttachments(pvs map[string]*corev1.PersistentVolume) ([]*Orphan, error) {
+	orphans := make([]*Orphan, 0)
+
+	vaList, err := p.k8scli.StorageV1().VolumeAttachments().List(metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// VolumeAttachment is not served by old clusters
+			return orphans, nil
+		}
+		return nil, fmt.Errorf("get info about volumeattachments failed, err: %v", err)
+	}
+
+	nodeList, err := p.k8scli.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about nodes failed, err: %v", err)
+	}
+	nodes := make(map[string]bool)
+	for _, node := range nodeList.Items {
+		nodes[node.Name] = true
+	}
+
+	for i := range vaList.Items {
+		if o := checkOrphanVolumeAttachment(&vaList.Items[i], nodes, pvs); o != nil {
+			orphans = append(orphans, o)
+		}
+	}
+	return orphans, nil
+}
+
+// checkOrphanVolumeAttachment returns nil if both the node and the pv of volumeattachment exist. The capacity
+// is left empty, since the pv is either reported as orphan by itself or in use, it should not be counted twice.
+func checkOrphanVolumeAttachment(va *storagev1.VolumeAttachment, nodes map[string]bool, pvs map[string]*corev1.PersistentVolume) *Orphan {
+	reasons := make([]string, 0)
+	if !nodes[va.Spec.NodeName] {
+		reasons = append(reasons, fmt.Sprintf("node %s is deleted", va.Spec.NodeName))
+	}
+	if name := va.Spec.Source.PersistentVolumeName; name != nil {
+		if _, ok := pvs[*name]; !ok {
+			reasons = append(reasons, fmt.Sprintf("pv %s is deleted", *name))
+		}
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+	return newOrphan(KindVolumeAttachment, "", va.Name, nil, reasons...)
+}
+
+// getPvcCapacity returns the actual capacity of pvc, or the requested one if it is not bound
+func getPvcCapacity(pvc *corev1.PersistentVolumeClaim) *resource.Quantity {
+	if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+		return &capacity
+	}
+	if request, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		return &request
+	}
+	return nil
+}
+
+func (o *Orphan) ReasonString() string {
+	return strings.Join(o.Reasons, "; ")
+}
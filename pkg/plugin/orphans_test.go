@@ -0,0 +1,166 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newTestPod(namespace, name string, claims ...string) corev1.Pod {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	for _, claim := range claims {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name:         claim,
+			VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim}},
+		})
+	}
+	return pod
+}
+
+func TestCheckOrphanPvc(t *testing.T) {
+	pods := []corev1.Pod{
+		newTestPod("team-a", "web-0", "data"),
+		newTestPod("team-b", "web-0", "cache"),
+	}
+	referenced := map[string]bool{"team-a/www-web-1": true}
+
+	tests := []struct {
+		namespace string
+		name      string
+		orphan    bool
+	}{
+		{"team-a", "data", false},
+		{"team-a", "www-web-1", false},
+		// the pod using pvc of the same name is in another namespace
+		{"team-a", "cache", true},
+		{"team-b", "data", true},
+	}
+	for _, test := range tests {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: test.namespace, Name: test.name},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		}
+		o := checkOrphanPvc(pvc, pods, referenced)
+		if (o != nil) != test.orphan {
+			t.Errorf("pvc %s/%s: expected orphan %v, got %+v", test.namespace, test.name, test.orphan, o)
+			continue
+		}
+		if o != nil && o.Capacity != "10Gi" {
+			t.Errorf("pvc %s/%s: expected capacity 10Gi, got %q", test.namespace, test.name, o.Capacity)
+		}
+	}
+}
+
+func TestCheckOrphanPv(t *testing.T) {
+	pvcUIDs := map[string]types.UID{"team-a/data": "uid-1"}
+	newPv := func(phase corev1.PersistentVolumePhase, ref *corev1.ObjectReference) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: corev1.PersistentVolumeSpec{
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("5Gi")},
+				ClaimRef: ref,
+			},
+			Status: corev1.PersistentVolumeStatus{Phase: phase},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pv      *corev1.PersistentVolume
+		reasons []string
+	}{
+		{
+			name: "bound to existing pvc",
+			pv:   newPv(corev1.VolumeBound, &corev1.ObjectReference{Namespace: "team-a", Name: "data", UID: "uid-1"}),
+		},
+		{
+			name: "pre-bound to existing pvc without uid",
+			pv:   newPv(corev1.VolumeBound, &corev1.ObjectReference{Namespace: "team-a", Name: "data"}),
+		},
+		{
+			name:    "available",
+			pv:      newPv(corev1.VolumeAvailable, nil),
+			reasons: []string{"pv is Available"},
+		},
+		{
+			name:    "released by deleted pvc",
+			pv:      newPv(corev1.VolumeReleased, &corev1.ObjectReference{Namespace: "team-a", Name: "old"}),
+			reasons: []string{"pv is Released", "claimRef points at missing pvc team-a/old"},
+		},
+		{
+			name:    "pvc is recreated with the same name",
+			pv:      newPv(corev1.VolumeReleased, &corev1.ObjectReference{Namespace: "team-a", Name: "data", UID: "uid-0"}),
+			reasons: []string{"pv is Released", "claimRef uid uid-0 does not match pvc team-a/data"},
+		},
+	}
+	for _, test := range tests {
+		o := checkOrphanPv(test.pv, pvcUIDs)
+		if test.reasons == nil {
+			if o != nil {
+				t.Errorf("%s: expected no orphan, got %+v", test.name, o)
+			}
+			continue
+		}
+		if o == nil {
+			t.Errorf("%s: expected orphan with reasons %v, got nil", test.name, test.reasons)
+			continue
+		}
+		if !reflect.DeepEqual(o.Reasons, test.reasons) || o.Capacity != "5Gi" {
+			t.Errorf("%s: expected reasons %v with capacity 5Gi, got %v with %q", test.name, test.reasons, o.Reasons, o.Capacity)
+		}
+	}
+}
+
+func TestCheckOrphanVolumeAttachment(t *testing.T) {
+	nodes := map[string]bool{"node-1": true}
+	pvs := map[string]*corev1.PersistentVolume{
+		"pv-1": {
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec:       corev1.PersistentVolumeSpec{Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("5Gi")}},
+		},
+	}
+	newVa := func(node, pv string) *storagev1.VolumeAttachment {
+		return &storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: "csi-" + node + "-" + pv},
+			Spec: storagev1.VolumeAttachmentSpec{
+				NodeName: node,
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pv},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		va      *storagev1.VolumeAttachment
+		reasons []string
+	}{
+		{"node and pv exist", newVa("node-1", "pv-1"), nil},
+		{"node is deleted", newVa("node-2", "pv-1"), []string{"node node-2 is deleted"}},
+		{"pv is deleted", newVa("node-1", "pv-2"), []string{"pv pv-2 is deleted"}},
+		{"both are deleted", newVa("node-2", "pv-2"), []string{"node node-2 is deleted", "pv pv-2 is deleted"}},
+	}
+	for _, test := range tests {
+		o := checkOrphanVolumeAttachment(test.va, nodes, pvs)
+		if test.reasons == nil {
+			if o != nil {
+				t.Errorf("%s: expected no orphan, got %+v", test.name, o)
+			}
+			continue
+		}
+		if o == nil || !reflect.DeepEqual(o.Reasons, test.reasons) {
+			t.Errorf("%s: expected reasons %v, got %+v", test.name, test.reasons, o)
+			continue
+		}
+		// the capacity of pv is counted by the pv or pvc, not the volumeattachment
+		if o.Capacity != "" || !o.capacity.IsZero() {
+			t.Errorf("%s: expected no capacity, got %q", test.name, o.Capacity)
+		}
+	}
+}
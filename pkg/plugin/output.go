@@ -0,0 +1,115 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	OutputWide = "wide"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// version of the machine-readable output, it should be bumped on incompatible changes of the printed types
+const (
+	OutputAPIVersion = "kubectl-pvc/v1alpha1"
+	PvcStatusKind    = "PvcStatus"
+	NodeStatusKind   = "NodeStatus"
+	OrphanReportKind = "OrphanReport"
+	PvcUsageListKind = "PvcUsageList"
+	SnapshotListKind = "SnapshotList"
+)
+
+// PrintPvcStatus prints the status of pvc in json or yaml
+func PrintPvcStatus(out io.Writer, status *PvcStatus, output string) error {
+	return printVersioned(out, PvcStatusKind, status, output)
+}
+
+// PrintNodeStatus prints the volumes of node in json or yaml
+func PrintNodeStatus(out io.Writer, status *NodeStatus, output string) error {
+	return printVersioned(out, NodeStatusKind, status, output)
+}
+
+// PrintPvcsUsage prints the filesystem usage of pvcs in json or yaml
+func PrintPvcsUsage(out io.Writer, usages []*PvcUsage, output string) error {
+	return printVersioned(out, PvcUsageListKind, usages, output)
+}
+
+// PrintSnapshots prints snapshots in json or yaml
+func PrintSnapshots(out io.Writer, snapshots []*Snapshot, output string) error {
+	return printVersioned(out, SnapshotListKind, snapshots, output)
+}
+
+// PrintOrphans prints the orphan storage in json or yaml
+func PrintOrphans(out io.Writer, report *OrphanReport, output string) error {
+	return printVersioned(out, OrphanReportKind, report, output)
+}
+
+// printVersioned prints obj in json or yaml with apiVersion and kind in front of its fields,
+// a slice is printed as the items of a list
+func printVersioned(out io.Writer, kind string, obj interface{}, output string) error {
+	if reflect.ValueOf(obj).Kind() == reflect.Slice {
+		obj = struct {
+			Items interface{} `json:"items"`
+		}{obj}
+	}
+	if err := printJSONOrYAML(out, versioned{kind: kind, obj: obj}, output); err != nil {
+		return fmt.Errorf("print %s failed, err: %v", kind, err)
+	}
+	return nil
+}
+
+// versioned marshals the fields of obj after apiVersion and kind
+type versioned struct {
+	kind string
+	obj  interface{}
+}
+
+func (v versioned) MarshalJSON() ([]byte, error) {
+	header, err := json.Marshal(struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+	}{OutputAPIVersion, v.kind})
+	if err != nil {
+		return nil, err
+	}
+	fields, err := json.Marshal(v.obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 2 || fields[0] != '{' {
+		return nil, fmt.Errorf("%s is not printed as an object", v.kind)
+	}
+	if string(fields) == "{}" {
+		return header, nil
+	}
+	data := append(header[:len(header)-1], ',')
+	return append(data, fields[1:]...), nil
+}
+
+func printJSONOrYAML(out io.Writer, obj interface{}, output string) error {
+	data, err := json.MarshalIndent(obj, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case OutputJSON:
+		data = append(data, '\n')
+	case OutputYAML:
+		data, err = yaml.JSONToYAML(data)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+
+	_, err = out.Write(data)
+	return err
+}
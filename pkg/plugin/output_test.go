@@ -0,0 +1,52 @@
+package plugin
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintVersioned(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   string
+		obj    interface{}
+		output string
+		want   string
+	}{
+		{
+			name:   "object",
+			kind:   PvcStatusKind,
+			obj:    &PvcStatus{Name: "data", Namespace: "team-a"},
+			output: OutputJSON,
+			want:   `"apiVersion": "kubectl-pvc/v1alpha1",` + "\n" + `    "kind": "PvcStatus",` + "\n" + `    "name": "data",`,
+		},
+		{
+			name:   "list",
+			kind:   SnapshotListKind,
+			obj:    []*Snapshot{},
+			output: OutputJSON,
+			want:   `"kind": "SnapshotList",` + "\n" + `    "items": []`,
+		},
+		{
+			name:   "yaml",
+			kind:   PvcUsageListKind,
+			obj:    []*PvcUsage(nil),
+			output: OutputYAML,
+			want:   "apiVersion: kubectl-pvc/v1alpha1\nitems: null\nkind: PvcUsageList\n",
+		},
+	}
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		if err := printVersioned(buf, test.kind, test.obj, test.output); err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if !bytes.Contains(buf.Bytes(), []byte(test.want)) {
+			t.Errorf("%s: expected output containing %q, got %q", test.name, test.want, buf.String())
+		}
+	}
+
+	if err := printVersioned(&bytes.Buffer{}, PvcStatusKind, &PvcStatus{}, "wide"); err == nil {
+		t.Errorf("expected error of unsupported output format")
+	}
+}
@@ -0,0 +1,148 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const OutputCustomColumns = "custom-columns"
+
+// NewPvcList wraps pvcs into a list of kind List the same as kubectl get, it is unstructured so that
+// every printer of cli-runtime including the name printer can print the list at once
+func NewPvcList(pvcs []corev1.PersistentVolumeClaim) (*unstructured.UnstructuredList, error) {
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+	list.SetAPIVersion("v1")
+	list.SetKind("List")
+	for i := range pvcs {
+		pvc := pvcs[i].DeepCopy()
+		pvc.APIVersion = "v1"
+		pvc.Kind = "PersistentVolumeClaim"
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pvc)
+		if err != nil {
+			return nil, fmt.Errorf("convert pvc [%s/%s] failed, err: %v", pvc.Namespace, pvc.Name, err)
+		}
+		list.Items = append(list.Items, unstructured.Unstructured{Object: obj})
+	}
+	return list, nil
+}
+
+type column struct {
+	header string
+	parser *jsonpath.JSONPath
+}
+
+// CustomColumnsPrinter prints one row for each object with the columns of spec,
+// spec is in the same format as kubectl get -o custom-columns=NAME:.metadata.name,...
+type CustomColumnsPrinter struct {
+	columns []column
+}
+
+func NewCustomColumnsPrinter(spec string) (*CustomColumnsPrinter, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("custom-columns format specified but no custom columns given")
+	}
+
+	p := &CustomColumnsPrinter{}
+	for _, part := range strings.Split(spec, ",") {
+		colSpec := strings.SplitN(part, ":", 2)
+		if len(colSpec) != 2 || colSpec[0] == "" || colSpec[1] == "" {
+			return nil, fmt.Errorf("unexpected custom-columns spec: %s, expected <header>:<json-path-expr>", part)
+		}
+		expr, err := relaxedJSONPathExpression(colSpec[1])
+		if err != nil {
+			return nil, err
+		}
+		parser := jsonpath.New(colSpec[0]).AllowMissingKeys(true)
+		if err := parser.Parse(expr); err != nil {
+			return nil, fmt.Errorf("parse custom-columns spec %s failed, err: %v", part, err)
+		}
+		p.columns = append(p.columns, column{header: colSpec[0], parser: parser})
+	}
+	return p, nil
+}
+
+func (p *CustomColumnsPrinter) PrintObj(obj runtime.Object, out io.Writer) error {
+	objs := []runtime.Object{obj}
+	if meta.IsListType(obj) {
+		items, err := meta.ExtractList(obj)
+		if err != nil {
+			return err
+		}
+		objs = items
+	}
+
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	headers := make([]string, 0, len(p.columns))
+	for _, col := range p.columns {
+		headers = append(headers, col.header)
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+
+	for _, o := range objs {
+		data, err := toUnstructured(o)
+		if err != nil {
+			return err
+		}
+		values := make([]string, 0, len(p.columns))
+		for _, col := range p.columns {
+			v, err := findColumnValue(col.parser, data)
+			if err != nil {
+				return err
+			}
+			values = append(values, v)
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	return w.Flush()
+}
+
+func toUnstructured(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return u.UnstructuredContent(), nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+func findColumnValue(parser *jsonpath.JSONPath, data interface{}) (string, error) {
+	results, err := parser.FindResults(data)
+	if err != nil {
+		return "", err
+	}
+	values := make([]string, 0)
+	for _, result := range results {
+		for _, r := range result {
+			buf := &bytes.Buffer{}
+			if err := parser.PrintResults(buf, []reflect.Value{r}); err != nil {
+				return "", err
+			}
+			values = append(values, buf.String())
+		}
+	}
+	if len(values) == 0 {
+		return "<none>", nil
+	}
+	return strings.Join(values, ","), nil
+}
+
+// relaxedJSONPathExpression accepts {.a.b}, .a.b and a.b the same way kubectl does
+func relaxedJSONPathExpression(expr string) (string, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "{") != strings.HasSuffix(expr, "}") {
+		return "", fmt.Errorf("unbalanced braces in jsonpath expression %s", expr)
+	}
+	expr = strings.TrimSuffix(strings.TrimPrefix(expr, "{"), "}")
+	if !strings.HasPrefix(expr, ".") {
+		expr = "." + expr
+	}
+	return fmt.Sprintf("{%s}", expr), nil
+}
@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/genericclioptions/printers"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestPrintPvcList(t *testing.T) {
+	pvcs := []corev1.PersistentVolumeClaim{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "data"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "logs"}},
+	}
+	customColumns, err := NewCustomColumnsPrinter("NAME:.metadata.name,NAMESPACE:metadata.namespace")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	tests := []struct {
+		output   string
+		expected string
+	}{
+		{
+			output:   "name",
+			expected: "persistentvolumeclaim/data\npersistentvolumeclaim/logs\n",
+		},
+		{
+			output:   "jsonpath={.kind} {.items[*].metadata.name}",
+			expected: "List data logs",
+		},
+		{
+			output:   "json",
+			expected: "\"kind\": \"PersistentVolumeClaim\"",
+		},
+		{
+			output:   OutputCustomColumns,
+			expected: "NAMESPACE\ndata      team-a\nlogs      team-a\n",
+		},
+	}
+	for _, test := range tests {
+		list, err := NewPvcList(pvcs)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		var printer printers.ResourcePrinter = customColumns
+		if test.output != OutputCustomColumns {
+			flags := genericclioptions.NewPrintFlags("").WithTypeSetter(scheme.Scheme)
+			*flags.OutputFormat = test.output
+			if printer, err = flags.ToPrinter(); err != nil {
+				t.Fatalf("%s: unexpected error %v", test.output, err)
+			}
+		}
+
+		out := &bytes.Buffer{}
+		if err := printer.PrintObj(list, out); err != nil {
+			t.Errorf("%s: unexpected error %v", test.output, err)
+			continue
+		}
+		if !strings.Contains(out.String(), test.expected) {
+			t.Errorf("%s: expected output containing %q, got %q", test.output, test.expected, out.String())
+		}
+	}
+}
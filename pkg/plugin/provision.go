@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	annStorageProvisioner      = "volume.beta.kubernetes.io/storage-provisioner"
+	annSelectedNode            = "volume.kubernetes.io/selected-node"
+	annBetaStorageClass        = "volume.beta.kubernetes.io/storage-class"
+	annDefaultStorageClass     = "storageclass.kubernetes.io/is-default-class"
+	annBetaDefaultStorageClass = "storageclass.beta.kubernetes.io/is-default-class"
+
+	// provisioner of storageclass which only supports pre-provisioned pv
+	noProvisioner = "kubernetes.io/no-provisioner"
+)
+
+// deducePhaseProvision explains why the pvc is not bound to any pv yet,
+// it follows the way pv controller picks the storageclass and the provisioner of one pvc
+func (p *PvcContext) deducePhaseProvision(objs *clusterObjects, pvc *corev1.PersistentVolumeClaim, pods []*Pod, events []*Event) (*PvcPhase, error) {
+	phase := &PvcPhase{
+		Name: PvcProvision,
+	}
+
+	if pvc.Spec.VolumeName != "" {
+		phase.Status = PvcPhaseSuccess
+		return phase, nil
+	}
+
+	className, ok := getPvcStorageClassName(pvc)
+	if !ok {
+		sc, err := p.getDefaultStorageClass(objs)
+		if err != nil {
+			return phase, err
+		}
+		if sc == nil {
+			phase.Status = PvcPhaseFail
+			phase.Detail = "no storageclass is set and there is no default storageclass, waiting for a matching pre-provisioned pv"
+			return phase, nil
+		}
+		// the default storageclass is written to pvc by admission plugin when it is created,
+		// so the pvc was created before the default storageclass.
+		phase.Status = PvcPhaseFail
+		phase.Detail = fmt.Sprintf("no storageclass is set, default storageclass %s is only applied to pvc created after it, waiting for a matching pre-provisioned pv", sc.Name)
+		return phase, nil
+	}
+
+	if className == "" {
+		phase.Status = PvcPhaseFail
+		phase.Detail = "storageclass is set to empty which disables dynamic provisioning, waiting for a matching pre-provisioned pv"
+		return phase, nil
+	}
+
+	sc, err := p.getStorageClass(objs, className)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			phase.Status = PvcPhaseFail
+			phase.Detail = fmt.Sprintf("storageclass %s is not found", className)
+			return phase, nil
+		}
+		return phase, fmt.Errorf("get info about storageclass [%s] failed, err: %v", className, err)
+	}
+
+	bindingMode := storagev1.VolumeBindingImmediate
+	if sc.VolumeBindingMode != nil {
+		bindingMode = *sc.VolumeBindingMode
+	}
+	scInfo := fmt.Sprintf("storageclass: %s, provisioner: %s, volumeBindingMode: %s", sc.Name, sc.Provisioner, bindingMode)
+
+	if sc.Provisioner == noProvisioner {
+		phase.Status = PvcPhaseFail
+		phase.Detail = fmt.Sprintf("storageclass has no provisioner, waiting for a matching pre-provisioned pv (%s)", scInfo)
+		return phase, nil
+	}
+
+	if bindingMode == storagev1.VolumeBindingWaitForFirstConsumer && pvc.Annotations[annSelectedNode] == "" {
+		phase.Status = PvcPhaseOndoing
+		phase.waitForFirstConsumer = true
+		if len(pods) == 0 {
+			phase.Detail = fmt.Sprintf("waiting for first consumer, no pod is using this pvc (%s)", scInfo)
+		} else {
+			phase.Detail = fmt.Sprintf("waiting for first consumer pods: [%s] to be scheduled (%s)", strings.Join(podNames(pods), ","), scInfo)
+		}
+		return phase, nil
+	}
+
+	sortEvents(events)
+	if event := latestEventOfReason(events, eventReasonProvisioningFailed); event != nil {
+		phase.Status = PvcPhaseFail
+		phase.Detail = fmt.Sprintf("provisioning failed: %s (%s)", event.Message, scInfo)
+		phase.hard = true
+		return phase, nil
+	}
+
+	phase.Status = PvcPhaseOndoing
+	if provisioner := pvc.Annotations[annStorageProvisioner]; provisioner != "" {
+		phase.Detail = fmt.Sprintf("waiting for provisioner %s to create volume (%s)", provisioner, scInfo)
+	} else {
+		phase.Detail = fmt.Sprintf("waiting for pv controller to start provisioning (%s)", scInfo)
+	}
+	if node := pvc.Annotations[annSelectedNode]; node != "" {
+		phase.Detail = fmt.Sprintf("%s, selected node: %s", phase.Detail, node)
+	}
+	return phase, nil
+}
+
+// getPvcStorageClassName returns false if the pvc does not set storageclass at all
+func getPvcStorageClassName(pvc *corev1.PersistentVolumeClaim) (string, bool) {
+	if class, ok := pvc.Annotations[annBetaStorageClass]; ok {
+		return class, true
+	}
+	if pvc.Spec.StorageClassName != nil {
+		return *pvc.Spec.StorageClassName, true
+	}
+	return "", false
+}
+
+func (p *PvcContext) getDefaultStorageClass(objs *clusterObjects) (*storagev1.StorageClass, error) {
+	scs, err := p.listStorageClasses(objs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range scs {
+		sc := &scs[i]
+		if sc.Annotations[annDefaultStorageClass] == "true" || sc.Annotations[annBetaDefaultStorageClass] == "true" {
+			return sc, nil
+		}
+	}
+	return nil, nil
+}
+
+func podNames(pods []*Pod) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
@@ -0,0 +1,266 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ResizePlan is the expansion of pvc to be done
+type ResizePlan struct {
+	Namespace    string
+	Name         string
+	StorageClass string
+	From         resource.Quantity
+	To           resource.Quantity
+	// running pods using the pvc, they must be restarted if the volume only supports offline expansion
+	Pods []string
+}
+
+// PlanResize checks whether the pvc can be expanded to the size
+func (p *PvcContext) PlanResize(namespace, pvcname string, size resource.Quantity) (*ResizePlan, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+	if namespace == "" {
+		namespace = p.namespace
+	}
+
+	cli := p.k8scli
+	pvc, err := cli.CoreV1().PersistentVolumeClaims(namespace).Get(pvcname, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", namespace, pvcname, err)
+	}
+	if pvc.Status.Phase != corev1.ClaimBound {
+		return nil, fmt.Errorf("pvc [%s/%s] is %s, only bound pvc can be expanded", namespace, pvcname, pvc.Status.Phase)
+	}
+
+	class, _ := getPvcStorageClassName(pvc)
+	if class == "" {
+		return nil, fmt.Errorf("pvc [%s/%s] has no storageclass, it can not be expanded", namespace, pvcname)
+	}
+	sc, err := cli.StorageV1().StorageClasses().Get(class, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about storageclass [%s] failed, err: %v", class, err)
+	}
+	if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+		return nil, fmt.Errorf("storageclass [%s] does not set allowVolumeExpansion, pvc [%s/%s] can not be expanded", class, namespace, pvcname)
+	}
+
+	current := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	switch size.Cmp(current) {
+	case -1:
+		return nil, fmt.Errorf("pvc [%s/%s] can not be shrunk from %s to %s", namespace, pvcname, current.String(), size.String())
+	case 0:
+		return nil, fmt.Errorf("pvc [%s/%s] already requests %s", namespace, pvcname, current.String())
+	}
+
+	podList, err := cli.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about pods of namespace [%s] failed, err: %v", namespace, err)
+	}
+	pods := make([]string, 0)
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if used, _ := isPvcUsedByPod(pvcname, pod); used {
+			pods = append(pods, pod.Name)
+		}
+	}
+
+	return &ResizePlan{
+		Namespace:    namespace,
+		Name:         pvcname,
+		StorageClass: class,
+		From:         current,
+		To:           size,
+		Pods:         pods,
+	}, nil
+}
+
+// ResizePvc patches the storage request of pvc as planned
+func (p *PvcContext) ResizePvc(plan *ResizePlan) error {
+	patch := fmt.Sprintf(`{"spec":{"resources":{"requests":{"storage":%q}}}}`, plan.To.String())
+	_, err := p.k8scli.CoreV1().PersistentVolumeClaims(plan.Namespace).Patch(plan.Name, types.StrategicMergePatchType, []byte(patch))
+	if err != nil {
+		return fmt.Errorf("patch storage request of pvc [%s/%s] failed, err: %v", plan.Namespace, plan.Name, err)
+	}
+	return nil
+}
+
+// WaitPvcResized waits until the capacity of pvc reaches the size, report is called every time the resize
+// conditions of pvc change, it returns ErrTimeout if timeout is not zero and expires, or the reason as soon as
+// the expansion fails, e.g. ControllerResizeError, NodeResizeError or VolumeResizeFailed
+func (p *PvcContext) WaitPvcResized(namespace, pvcname string, size resource.Quantity, timeout time.Duration,
+	report func([]corev1.PersistentVolumeClaimCondition)) error {
+	if namespace == "" {
+		namespace = p.namespace
+	}
+
+	// events of earlier expansions are ignored, timestamps of events are in seconds
+	start := time.Now().Truncate(time.Second)
+	var (
+		last    string
+		lastErr error
+	)
+	err := p.watchUntil(namespace, timeout, func() bool {
+		pvc, err := p.k8scli.CoreV1().PersistentVolumeClaims(namespace).Get(pvcname, metav1.GetOptions{})
+		if err != nil {
+			lastErr = fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", namespace, pvcname, err)
+			return true
+		}
+		conditions := resizeConditions(pvc)
+		s := ""
+		for _, c := range conditions {
+			s += fmt.Sprintf("%s:%s;", c.Type, c.Message)
+		}
+		if s != last {
+			report(conditions)
+			last = s
+		}
+		capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+		if ok && capacity.Cmp(size) >= 0 {
+			return true
+		}
+
+		pv, err := p.k8scli.CoreV1().PersistentVolumes().Get(pvc.Spec.VolumeName, metav1.GetOptions{})
+		if err != nil {
+			lastErr = fmt.Errorf("get info about pv [%s] failed, err: %v", pvc.Spec.VolumeName, err)
+			return true
+		}
+		events, err := p.listPhaseEvents(newClusterObjects(false), namespace, pvc.UID)
+		if err != nil {
+			lastErr = err
+			return true
+		}
+		if phase := deducePhaseResize(pvc, pv, nil, eventsSince(events, start)); phase.Status == PvcPhaseFail {
+			lastErr = fmt.Errorf("expansion of pvc [%s/%s] failed, %s", namespace, pvcname, phase.Detail)
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	return lastErr
+}
+
+func eventsSince(events []*Event, t time.Time) []*Event {
+	since := make([]*Event, 0, len(events))
+	for _, e := range events {
+		if !e.LastTimestamp.Before(t) {
+			since = append(since, e)
+		}
+	}
+	return since
+}
+
+// resizeConditions returns the conditions of pvc about expansion
+func resizeConditions(pvc *corev1.PersistentVolumeClaim) []corev1.PersistentVolumeClaimCondition {
+	conditions := make([]corev1.PersistentVolumeClaimCondition, 0)
+	for _, c := range pvc.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case corev1.PersistentVolumeClaimResizing, corev1.PersistentVolumeClaimFileSystemResizePending:
+			conditions = append(conditions, c)
+		}
+	}
+	return conditions
+}
+
+// conditions of pvc set by external resizer and kubelet when expansion fails, they are not defined in old api
+const (
+	pvcConditionControllerResizeError corev1.PersistentVolumeClaimConditionType = "ControllerResizeError"
+	pvcConditionNodeResizeError       corev1.PersistentVolumeClaimConditionType = "NodeResizeError"
+)
+
+// deducePhaseResize compares the storage request of pvc with the capacity of pvc and pv to find out whether
+// the expansion is done, waiting for the resizer, waiting for the file system resize on node or failed
+func deducePhaseResize(pvc *corev1.PersistentVolumeClaim, pv *corev1.PersistentVolume, pods []*Pod, events []*Event) *PvcPhase {
+	phase := &PvcPhase{
+		Name: PvcResize,
+	}
+
+	resizeEvents := make([]*Event, 0)
+	for _, e := range events {
+		if e.Phase == PvcResize {
+			resizeEvents = append(resizeEvents, e)
+		}
+	}
+	sortEvents(resizeEvents)
+
+	request := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	pvCapacity := pv.Spec.Capacity[corev1.ResourceStorage]
+
+	if request.Cmp(capacity) <= 0 {
+		if len(resizeEvents) == 0 {
+			phase.Status = PvcPhaseNotApplicable
+			phase.Detail = "no expansion is requested"
+			return phase
+		}
+		phase.Status = PvcPhaseSuccess
+		phase.Detail = fmt.Sprintf("expanded to %s", capacity.String())
+		return phase
+	}
+
+	for _, c := range pvc.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		if c.Type == pvcConditionControllerResizeError || c.Type == pvcConditionNodeResizeError {
+			phase.Status = PvcPhaseFail
+			phase.Detail = fmt.Sprintf("%s: %s", c.Type, firstLine(c.Message))
+			phase.hard = true
+			return phase
+		}
+	}
+
+	// failure events are retried by external-resizer and kubelet, so they are not hard failures
+	if len(resizeEvents) > 0 && resizeEvents[0].Type == corev1.EventTypeWarning {
+		phase.Status = PvcPhaseFail
+		phase.Detail = fmt.Sprintf("expansion from %s to %s failed", capacity.String(), request.String())
+		return phase
+	}
+
+	phase.Status = PvcPhaseOndoing
+	if pvCapacity.Cmp(request) >= 0 || hasPvcCondition(pvc, corev1.PersistentVolumeClaimFileSystemResizePending) {
+		mounted := make([]string, 0)
+		for _, pod := range pods {
+			if pod.MountStatus == PodMountMounted {
+				mounted = append(mounted, pod.Name)
+			}
+		}
+		if len(mounted) == 0 {
+			phase.Detail = fmt.Sprintf("volume is expanded to %s, file system is resized when a pod mounts it", pvCapacity.String())
+		} else {
+			phase.Detail = fmt.Sprintf("volume is expanded to %s, waiting for kubelet to resize file system, restart pods %v if the volume only supports offline expansion",
+				pvCapacity.String(), mounted)
+		}
+		return phase
+	}
+
+	resizer := "expand controller"
+	if pv.Spec.CSI != nil {
+		resizer = fmt.Sprintf("external resizer of %s", pv.Spec.CSI.Driver)
+	}
+	phase.Detail = fmt.Sprintf("waiting for %s to expand volume from %s to %s", resizer, pvCapacity.String(), request.String())
+	return phase
+}
+
+func hasPvcCondition(pvc *corev1.PersistentVolumeClaim, t corev1.PersistentVolumeClaimConditionType) bool {
+	for _, c := range pvc.Status.Conditions {
+		if c.Type == t && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDeducePhaseResize(t *testing.T) {
+	start := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	newEvent := func(eventType, reason string, at time.Duration) *Event {
+		return &Event{Phase: PvcResize, Type: eventType, Reason: reason, LastTimestamp: start.Add(at)}
+	}
+
+	tests := []struct {
+		name       string
+		capacity   string
+		pvCapacity string
+		conditions []corev1.PersistentVolumeClaimConditionType
+		events     []*Event
+		status     PvcPhaseStatus
+		detail     string
+	}{
+		{
+			name:       "no expansion",
+			capacity:   "20Gi",
+			pvCapacity: "20Gi",
+			status:     PvcPhaseNotApplicable,
+		},
+		{
+			name:       "expanded",
+			capacity:   "20Gi",
+			pvCapacity: "20Gi",
+			events:     []*Event{newEvent(corev1.EventTypeNormal, eventReasonVolumeResizeSucceeded, time.Second)},
+			status:     PvcPhaseSuccess,
+		},
+		{
+			name:       "waiting for resizer",
+			capacity:   "10Gi",
+			pvCapacity: "10Gi",
+			events:     []*Event{newEvent(corev1.EventTypeNormal, eventReasonResizing, time.Second)},
+			status:     PvcPhaseOndoing,
+			detail:     "waiting for expand controller to expand volume from 10Gi to 20Gi",
+		},
+		{
+			name:       "waiting for file system resize",
+			capacity:   "10Gi",
+			pvCapacity: "20Gi",
+			conditions: []corev1.PersistentVolumeClaimConditionType{corev1.PersistentVolumeClaimFileSystemResizePending},
+			status:     PvcPhaseOndoing,
+			detail:     "file system is resized when a pod mounts it",
+		},
+		{
+			name:       "controller resize error",
+			capacity:   "10Gi",
+			pvCapacity: "10Gi",
+			conditions: []corev1.PersistentVolumeClaimConditionType{pvcConditionControllerResizeError},
+			status:     PvcPhaseFail,
+			detail:     "ControllerResizeError: rpc error",
+		},
+		{
+			name:       "volume resize failed",
+			capacity:   "10Gi",
+			pvCapacity: "10Gi",
+			events: []*Event{
+				newEvent(corev1.EventTypeWarning, eventReasonVolumeResizeFailed, 2*time.Second),
+				newEvent(corev1.EventTypeNormal, eventReasonResizing, time.Second),
+			},
+			status: PvcPhaseFail,
+			detail: "expansion from 10Gi to 20Gi failed",
+		},
+		{
+			name:       "failure of earlier expansion",
+			capacity:   "10Gi",
+			pvCapacity: "10Gi",
+			events:     eventsSince([]*Event{newEvent(corev1.EventTypeWarning, eventReasonVolumeResizeFailed, -time.Minute)}, start),
+			status:     PvcPhaseOndoing,
+		},
+	}
+	for _, test := range tests {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "data"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")}},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(test.capacity)},
+			},
+		}
+		for _, c := range test.conditions {
+			pvc.Status.Conditions = append(pvc.Status.Conditions, corev1.PersistentVolumeClaimCondition{Type: c, Status: corev1.ConditionTrue, Message: "rpc error"})
+		}
+		pv := &corev1.PersistentVolume{
+			Spec: corev1.PersistentVolumeSpec{Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(test.pvCapacity)}},
+		}
+
+		phase := deducePhaseResize(pvc, pv, nil, test.events)
+		if phase.Status != test.status {
+			t.Errorf("%s: expected status %q, got %q: %s", test.name, test.status, phase.Status, phase.Detail)
+			continue
+		}
+		if test.detail != "" && !strings.Contains(phase.Detail, test.detail) {
+			t.Errorf("%s: expected detail containing %q, got %q", test.name, test.detail, phase.Detail)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// VolumeSnapshot is served as crd by the snapshot controller, so it is accessed as unstructured objects
+// through the dynamic client, v1alpha1 is not supported since its spec is not compatible with the later versions
+const (
+	snapshotGroup         = "snapshot.storage.k8s.io"
+	snapshotKind          = "VolumeSnapshot"
+	snapshotResource      = "volumesnapshots"
+	snapshotClassResource = "volumesnapshotclasses"
+)
+
+var supportedSnapshotVersions = []string{"v1", "v1beta1"}
+
+type Snapshot struct {
+	Name          string    `json:"name"`
+	Namespace     string    `json:"namespace"`
+	SourcePvc     string    `json:"sourcePvc"`
+	SnapshotClass string    `json:"snapshotClass,omitempty"`
+	Content       string    `json:"content,omitempty"`
+	ReadyToUse    bool      `json:"readyToUse"`
+	RestoreSize   string    `json:"restoreSize,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	CreationTime  time.Time `json:"creationTime"`
+}
+
+func NewSnapshot(u *unstructured.Unstructured) *Snapshot {
+	s := &Snapshot{
+		Name:         u.GetName(),
+		Namespace:    u.GetNamespace(),
+		CreationTime: u.GetCreationTimestamp().Time,
+	}
+	obj := u.Object
+	s.SourcePvc, _, _ = unstructured.NestedString(obj, "spec", "source", "persistentVolumeClaimName")
+	s.SnapshotClass, _, _ = unstructured.NestedString(obj, "spec", "volumeSnapshotClassName")
+	s.Content, _, _ = unstructured.NestedString(obj, "status", "boundVolumeSnapshotContentName")
+	s.ReadyToUse, _, _ = unstructured.NestedBool(obj, "status", "readyToUse")
+	s.Error, _, _ = unstructured.NestedString(obj, "status", "error", "message")
+	// restoreSize is a quantity which may be decoded as string or number
+	if size, ok, _ := unstructured.NestedFieldNoCopy(obj, "status", "restoreSize"); ok && size != nil {
+		s.RestoreSize = fmt.Sprintf("%v", size)
+	}
+	return s
+}
+
+// snapshotVersion returns the version of snapshot.storage.k8s.io preferred by apiserver
+func (p *PvcContext) snapshotVersion() (string, error) {
+	groups, err := p.k8scli.Discovery().ServerGroups()
+	if err != nil {
+		return "", fmt.Errorf("get api groups from kubernetes apiserver failed, err %v", err)
+	}
+	for _, group := range groups.Groups {
+		if group.Name != snapshotGroup {
+			continue
+		}
+		served := make(map[string]bool)
+		for _, v := range group.Versions {
+			served[v.Version] = true
+		}
+		if served[group.PreferredVersion.Version] && isSupportedSnapshotVersion(group.PreferredVersion.Version) {
+			return group.PreferredVersion.Version, nil
+		}
+		for _, v := range supportedSnapshotVersions {
+			if served[v] {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("none of versions %v of %s is served by apiserver", supportedSnapshotVersions, snapshotGroup)
+	}
+	return "", fmt.Errorf("%s is not served by apiserver, the snapshot crds and controller should be installed", snapshotGroup)
+}
+
+func isSupportedSnapshotVersion(version string) bool {
+	for _, v := range supportedSnapshotVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotGVR returns the resource of snapshot.storage.k8s.io in the version served by apiserver
+func snapshotGVR(version, resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: snapshotGroup, Version: version, Resource: resource}
+}
+
+type CreateSnapshotOption struct {
+	// generated from the name of pvc if empty
+	Name string
+	// the default VolumeSnapshotClass of the driver is used if empty
+	SnapshotClass string
+}
+
+// CreateSnapshot creates a VolumeSnapshot of the pvc, only pvc of csi volume can be snapshotted
+func (p *PvcContext) CreateSnapshot(namespace, pvcname string, opt CreateSnapshotOption) (*Snapshot, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+	if namespace == "" {
+		namespace = p.namespace
+	}
+
+	version, err := p.snapshotVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	cli := p.k8scli
+	pvc, err := cli.CoreV1().PersistentVolumeClaims(namespace).Get(pvcname, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", namespace, pvcname, err)
+	}
+	if pvc.Spec.VolumeName == "" {
+		return nil, fmt.Errorf("pvc [%s/%s] is not bound, it can not be snapshotted", namespace, pvcname)
+	}
+	pv, err := cli.CoreV1().PersistentVolumes().Get(pvc.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about pv [%s] failed, err: %v", pvc.Spec.VolumeName, err)
+	}
+	if pv.Spec.CSI == nil {
+		return nil, fmt.Errorf("pv [%s] is not a csi volume, it can not be snapshotted", pv.Name)
+	}
+
+	if opt.SnapshotClass != "" {
+		class, err := p.dyncli.Resource(snapshotGVR(version, snapshotClassResource)).Get(opt.SnapshotClass, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("get info about volumesnapshotclass [%s] failed, err: %v", opt.SnapshotClass, err)
+		}
+		if driver, _, _ := unstructured.NestedString(class.Object, "driver"); driver != pv.Spec.CSI.Driver {
+			return nil, fmt.Errorf("volumesnapshotclass [%s] is for driver %s, but pv [%s] is provisioned by %s",
+				opt.SnapshotClass, driver, pv.Name, pv.Spec.CSI.Driver)
+		}
+	}
+
+	name := opt.Name
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", pvcname, time.Now().Format("20060102150405"))
+	}
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": pvcname,
+		},
+	}
+	if opt.SnapshotClass != "" {
+		spec["volumeSnapshotClassName"] = opt.SnapshotClass
+	}
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": snapshotGroup + "/" + version,
+		"kind":       snapshotKind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"spec": spec,
+	}}
+
+	u, err = p.dyncli.Resource(snapshotGVR(version, snapshotResource)).Namespace(namespace).Create(u, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("create volumesnapshot [%s/%s] failed, err: %v", namespace, name, err)
+	}
+	return NewSnapshot(u), nil
+}
+
+// ListSnapshots lists the snapshots of the pvc, or all snapshots if pvcname is empty,
+// snapshots of the same pvc are sorted from the newest to the oldest
+func (p *PvcContext) ListSnapshots(pvcname string, allNamespaces bool) ([]*Snapshot, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+
+	version, err := p.snapshotVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := p.namespace
+	if allNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+	list, err := p.dyncli.Resource(snapshotGVR(version, snapshotResource)).Namespace(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list volumesnapshots from kubernetes apiserver failed, err %v", err)
+	}
+
+	snapshots := make([]*Snapshot, 0)
+	for i := range list.Items {
+		s := NewSnapshot(&list.Items[i])
+		if pvcname == "" || s.SourcePvc == pvcname {
+			snapshots = append(snapshots, s)
+		}
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if snapshots[i].Namespace != snapshots[j].Namespace {
+			return snapshots[i].Namespace < snapshots[j].Namespace
+		}
+		if snapshots[i].SourcePvc != snapshots[j].SourcePvc {
+			return snapshots[i].SourcePvc < snapshots[j].SourcePvc
+		}
+		return snapshots[i].CreationTime.After(snapshots[j].CreationTime)
+	})
+	return snapshots, nil
+}
+
+// RestoreSnapshot creates a new pvc from the snapshot, the storageclass, access modes and volume mode are copied
+// from the source pvc and the size is the larger one of the source pvc and restoreSize of the snapshot
+func (p *PvcContext) RestoreSnapshot(namespace, snapshotname, pvcname string, o PvcOverrides) (*corev1.PersistentVolumeClaim, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+	if namespace == "" {
+		namespace = p.namespace
+	}
+
+	version, err := p.snapshotVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := p.dyncli.Resource(snapshotGVR(version, snapshotResource)).Namespace(namespace).Get(snapshotname, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get info about volumesnapshot [%s/%s] failed, err: %v", namespace, snapshotname, err)
+	}
+	snapshot := NewSnapshot(u)
+	if !snapshot.ReadyToUse {
+		msg := "it is not ready to use"
+		if snapshot.Error != "" {
+			msg = fmt.Sprintf("%s, error: %s", msg, snapshot.Error)
+		}
+		return nil, fmt.Errorf("volumesnapshot [%s/%s] can not be restored, %s", namespace, snapshotname, msg)
+	}
+
+	var restoreSize resource.Quantity
+	if snapshot.RestoreSize != "" {
+		restoreSize, err = resource.ParseQuantity(snapshot.RestoreSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid restoreSize %q of volumesnapshot [%s/%s], err: %v", snapshot.RestoreSize, namespace, snapshotname, err)
+		}
+	}
+
+	// the source pvc may be deleted, then the spec should be given by the overrides
+	source, err := p.k8scli.CoreV1().PersistentVolumeClaims(namespace).Get(snapshot.SourcePvc, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("get info about pvc [%s/%s] failed, err: %v", namespace, snapshot.SourcePvc, err)
+		}
+		source = nil
+	}
+
+	apiGroup := snapshotGroup
+	pvc, err := newPvcFromSource(namespace, pvcname, source, restoreSize, &corev1.TypedLocalObjectReference{
+		APIGroup: &apiGroup,
+		Kind:     snapshotKind,
+		Name:     snapshotname,
+	}, o)
+	if err != nil {
+		return nil, err
+	}
+
+	pvc, err = p.k8scli.CoreV1().PersistentVolumeClaims(namespace).Create(pvc)
+	if err != nil {
+		return nil, fmt.Errorf("create pvc [%s/%s] failed, err: %v", namespace, pvcname, err)
+	}
+	return pvc, nil
+}
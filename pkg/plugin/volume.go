@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// names of volume plugins, they are the prefix of the unique volume name
+// reported by kubelet and attach/detach controller
+const (
+	csiPluginName      = "kubernetes.io/csi"
+	rbdPluginName      = "kubernetes.io/rbd"
+	cephfsPluginName   = "kubernetes.io/cephfs"
+	nfsPluginName      = "kubernetes.io/nfs"
+	iscsiPluginName    = "kubernetes.io/iscsi"
+	fcPluginName       = "kubernetes.io/fc"
+	hostPathPluginName = "kubernetes.io/host-path"
+	localPluginName    = "kubernetes.io/local-volume"
+	awsEBSPluginName   = "kubernetes.io/aws-ebs"
+	gcePDPluginName    = "kubernetes.io/gce-pd"
+)
+
+// get the plugin of this pv and the name of this pv which is displayed on the volumesAttached of Node,
+// the name is empty if this kind of pv is never attached to node
+func getAttachedVolumeName(pv *corev1.PersistentVolume) (plugin string, name string, err error) {
+	source := pv.Spec.PersistentVolumeSource
+	switch {
+	case source.CSI != nil:
+		return csiPluginName, uniqueVolumeName(csiPluginName, fmt.Sprintf("%s^%s", source.CSI.Driver, source.CSI.VolumeHandle)), nil
+	case source.RBD != nil:
+		return rbdPluginName, uniqueVolumeName(rbdPluginName, fmt.Sprintf("%s:%s", source.RBD.RBDPool, source.RBD.RBDImage)), nil
+	case source.ISCSI != nil:
+		return iscsiPluginName, uniqueVolumeName(iscsiPluginName, fmt.Sprintf("%v:%v:%v", source.ISCSI.TargetPortal, source.ISCSI.IQN, source.ISCSI.Lun)), nil
+	case source.FC != nil:
+		name, err := getFCVolumeName(source.FC)
+		if err != nil {
+			return fcPluginName, "", err
+		}
+		return fcPluginName, uniqueVolumeName(fcPluginName, name), nil
+	case source.AWSElasticBlockStore != nil:
+		return awsEBSPluginName, uniqueVolumeName(awsEBSPluginName, source.AWSElasticBlockStore.VolumeID), nil
+	case source.GCEPersistentDisk != nil:
+		return gcePDPluginName, uniqueVolumeName(gcePDPluginName, source.GCEPersistentDisk.PDName), nil
+	case source.CephFS != nil:
+		return cephfsPluginName, "", nil
+	case source.NFS != nil:
+		return nfsPluginName, "", nil
+	case source.HostPath != nil:
+		return hostPathPluginName, "", nil
+	case source.Local != nil:
+		return localPluginName, "", nil
+	}
+
+	return "", "", fmt.Errorf("the volume source of pv [%s] is now not supported", pv.Name)
+}
+
+func uniqueVolumeName(plugin, name string) string {
+	return plugin + "/" + name
+}
+
+// the same as GetVolumeName of fc volume plugin
+func getFCVolumeName(fc *corev1.FCVolumeSource) (string, error) {
+	if len(fc.TargetWWNs) != 0 && fc.Lun != nil {
+		return fmt.Sprintf("%v:%v", fc.TargetWWNs, *fc.Lun), nil
+	}
+	if len(fc.WWIDs) != 0 {
+		return fmt.Sprintf("%v", fc.WWIDs), nil
+	}
+	return "", fmt.Errorf("fc volume should have targetWWNs with lun or wwids, got targetWWNs: [%s], wwids: [%s]",
+		strings.Join(fc.TargetWWNs, ","), strings.Join(fc.WWIDs, ","))
+}
+
+// getVolumeHandle returns the id of the volume in its storage backend
+func getVolumeHandle(pv *corev1.PersistentVolume) string {
+	source := pv.Spec.PersistentVolumeSource
+	switch {
+	case source.CSI != nil:
+		return source.CSI.VolumeHandle
+	case source.RBD != nil:
+		return fmt.Sprintf("%s/%s", source.RBD.RBDPool, source.RBD.RBDImage)
+	case source.ISCSI != nil:
+		return fmt.Sprintf("%s:%d", source.ISCSI.IQN, source.ISCSI.Lun)
+	case source.FC != nil:
+		name, _ := getFCVolumeName(source.FC)
+		return name
+	case source.AWSElasticBlockStore != nil:
+		return source.AWSElasticBlockStore.VolumeID
+	case source.GCEPersistentDisk != nil:
+		return source.GCEPersistentDisk.PDName
+	case source.CephFS != nil:
+		return fmt.Sprintf("%s:%s", strings.Join(source.CephFS.Monitors, ","), source.CephFS.Path)
+	case source.NFS != nil:
+		return fmt.Sprintf("%s:%s", source.NFS.Server, source.NFS.Path)
+	case source.HostPath != nil:
+		return source.HostPath.Path
+	case source.Local != nil:
+		return source.Local.Path
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetAttachedVolumeName(t *testing.T) {
+	lun := int32(2)
+	tests := []struct {
+		name       string
+		source     corev1.PersistentVolumeSource
+		plugin     string
+		uniqueName string
+		err        bool
+	}{
+		{
+			name:       "csi",
+			source:     corev1.PersistentVolumeSource{CSI: &corev1.CSIPersistentVolumeSource{Driver: "rbd.csi.ceph.com", VolumeHandle: "0001-abcd"}},
+			plugin:     csiPluginName,
+			uniqueName: "kubernetes.io/csi/rbd.csi.ceph.com^0001-abcd",
+		},
+		{
+			name:       "rbd",
+			source:     corev1.PersistentVolumeSource{RBD: &corev1.RBDPersistentVolumeSource{RBDPool: "kube", RBDImage: "image-1"}},
+			plugin:     rbdPluginName,
+			uniqueName: "kubernetes.io/rbd/kube:image-1",
+		},
+		{
+			name:       "iscsi",
+			source:     corev1.PersistentVolumeSource{ISCSI: &corev1.ISCSIPersistentVolumeSource{TargetPortal: "10.0.0.1:3260", IQN: "iqn.2019-01.io.example:disk", Lun: 1}},
+			plugin:     iscsiPluginName,
+			uniqueName: "kubernetes.io/iscsi/10.0.0.1:3260:iqn.2019-01.io.example:disk:1",
+		},
+		{
+			name:       "fc with target wwns",
+			source:     corev1.PersistentVolumeSource{FC: &corev1.FCVolumeSource{TargetWWNs: []string{"500a0981891b8dc5", "500a0981991b8dc5"}, Lun: &lun}},
+			plugin:     fcPluginName,
+			uniqueName: "kubernetes.io/fc/[500a0981891b8dc5 500a0981991b8dc5]:2",
+		},
+		{
+			name:       "fc with wwids",
+			source:     corev1.PersistentVolumeSource{FC: &corev1.FCVolumeSource{WWIDs: []string{"3600508b400105e210000900000490000"}}},
+			plugin:     fcPluginName,
+			uniqueName: "kubernetes.io/fc/[3600508b400105e210000900000490000]",
+		},
+		{
+			name:   "fc without lun",
+			source: corev1.PersistentVolumeSource{FC: &corev1.FCVolumeSource{TargetWWNs: []string{"500a0981891b8dc5"}}},
+			plugin: fcPluginName,
+			err:    true,
+		},
+		{
+			name:       "aws ebs",
+			source:     corev1.PersistentVolumeSource{AWSElasticBlockStore: &corev1.AWSElasticBlockStoreVolumeSource{VolumeID: "aws://us-east-1a/vol-123"}},
+			plugin:     awsEBSPluginName,
+			uniqueName: "kubernetes.io/aws-ebs/aws://us-east-1a/vol-123",
+		},
+		{
+			name:       "gce pd",
+			source:     corev1.PersistentVolumeSource{GCEPersistentDisk: &corev1.GCEPersistentDiskVolumeSource{PDName: "disk-1"}},
+			plugin:     gcePDPluginName,
+			uniqueName: "kubernetes.io/gce-pd/disk-1",
+		},
+		{
+			name:   "cephfs is not attached",
+			source: corev1.PersistentVolumeSource{CephFS: &corev1.CephFSPersistentVolumeSource{Monitors: []string{"10.0.0.1:6789"}}},
+			plugin: cephfsPluginName,
+		},
+		{
+			name:   "nfs is not attached",
+			source: corev1.PersistentVolumeSource{NFS: &corev1.NFSVolumeSource{Server: "nfs", Path: "/export"}},
+			plugin: nfsPluginName,
+		},
+		{
+			name:   "hostpath is not attached",
+			source: corev1.PersistentVolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/data"}},
+			plugin: hostPathPluginName,
+		},
+		{
+			name:   "local is not attached",
+			source: corev1.PersistentVolumeSource{Local: &corev1.LocalVolumeSource{Path: "/mnt/disk"}},
+			plugin: localPluginName,
+		},
+		{
+			name:   "unsupported",
+			source: corev1.PersistentVolumeSource{Glusterfs: &corev1.GlusterfsPersistentVolumeSource{EndpointsName: "glusterfs"}},
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		pv := &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec:       corev1.PersistentVolumeSpec{PersistentVolumeSource: test.source},
+		}
+		plugin, uniqueName, err := getAttachedVolumeName(pv)
+		if (err != nil) != test.err {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+			continue
+		}
+		if plugin != test.plugin || uniqueName != test.uniqueName {
+			t.Errorf("%s: expected %q %q, got %q %q", test.name, test.plugin, test.uniqueName, plugin, uniqueName)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ParseWaitCondition parses the condition of --for, one of bound|attached|mounted|resized|phase=<phase>,
+// and returns the phase which should be successful
+func ParseWaitCondition(condition string) (PvcPhaseName, error) {
+	switch strings.ToLower(condition) {
+	case "bound":
+		return PvcBind, nil
+	case "attached":
+		return PvcAttach, nil
+	case "mounted":
+		return PvcMount, nil
+	case "resized":
+		return PvcResize, nil
+	}
+	if strings.HasPrefix(condition, "phase=") {
+		name := strings.TrimPrefix(condition, "phase=")
+		for _, phase := range allPhases {
+			if strings.EqualFold(name, string(phase)) {
+				return phase, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unsupported condition %q, should be one of: bound|attached|mounted|resized|phase=Provision|Bind|Attach|Mount|Resize", condition)
+}
+
+// isPhaseMet returns true if the phase is successful, not applicable also counts unless it is only because
+// no pod is using the pvc yet, in which case attach and mount are still expected
+func isPhaseMet(status *PvcStatus, name PvcPhaseName) bool {
+	phase := status.Phases[name]
+	return phase.Status == PvcPhaseSuccess || (phase.Status == PvcPhaseNotApplicable && !phase.noConsumer)
+}
+
+// hardFailure returns the phase up to the given one which failed and will not recover by itself, or nil.
+// Only explicit failures count, e.g. ProvisioningFailed or a pv bound to other pvc, while waiting for
+// a pre-provisioned pv or a storageclass to be created may still succeed later, and attach and mount
+// are retried by attach-detach controller and kubelet all the time.
+func hardFailure(status *PvcStatus, name PvcPhaseName) *PvcPhase {
+	for _, n := range allPhases {
+		if phase := status.Phases[n]; phase.hard && phase.Status == PvcPhaseFail {
+			return phase
+		}
+		if n == name {
+			break
+		}
+	}
+	return nil
+}
+
+// ErrWaitForFirstConsumer is returned by WaitPvcPhase when the pvc is not provisioned until a pod using it is scheduled
+var ErrWaitForFirstConsumer = errors.New("provisioning is delayed until a pod using the pvc is scheduled")
+
+// PvcFailedError is returned when some pvc is failed in a phase which will not recover by itself
+type PvcFailedError struct {
+	Namespace string
+	Name      string
+	Phase     *PvcPhase
+}
+
+func (e *PvcFailedError) Error() string {
+	return fmt.Sprintf("pvc [%s/%s] failed in phase %s: %s", e.Namespace, e.Name, e.Phase.Name, formatPhaseDetail(e.Phase))
+}
+
+// WaitTimeoutError is returned when some pvcs do not meet the condition before timeout
+type WaitTimeoutError struct {
+	// the reason why the condition is not met of every pending pvc
+	Pending map[string]string
+}
+
+func (e *WaitTimeoutError) Error() string {
+	names := make([]string, 0, len(e.Pending))
+	for name := range e.Pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s (%s)", name, e.Pending[name]))
+	}
+	return fmt.Sprintf("%v, pending pvcs: %s", ErrTimeout, strings.Join(msgs, ", "))
+}
+
+// WaitPvcs waits until the phase of all pvcs in the namespace is met, met is called once for each pvc when its phase is met.
+// It returns *PvcFailedError if any pvc fails hard, or *WaitTimeoutError if timeout is not zero and expires.
+func (p *PvcContext) WaitPvcs(namespace string, pvcnames []string, phase PvcPhaseName, timeout time.Duration, met func(name string)) error {
+	if p.k8scli == nil {
+		return fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+	if namespace == "" {
+		namespace = p.namespace
+	}
+
+	pending := make(map[string]string)
+	for _, name := range pvcnames {
+		pending[name] = "not checked yet"
+	}
+
+	var failed error
+	err := p.watchScopeUntil(namespace, timeout, func(scope *watchScope) bool {
+		for _, name := range pvcnames {
+			if _, ok := pending[name]; !ok {
+				continue
+			}
+			status, err := p.GetPvcDetail(namespace, name)
+			scope.addPvcStatus(status)
+			if err != nil {
+				// the pvc may be not created yet
+				pending[name] = err.Error()
+				continue
+			}
+			if isPhaseMet(status, phase) {
+				delete(pending, name)
+				met(name)
+				continue
+			}
+			if f := hardFailure(status, phase); f != nil {
+				failed = &PvcFailedError{Namespace: namespace, Name: name, Phase: f}
+				return true
+			}
+			pending[name] = formatPendingReason(status, phase)
+		}
+		return len(pending) == 0
+	})
+	if err == ErrTimeout {
+		return &WaitTimeoutError{Pending: pending}
+	}
+	if err != nil {
+		return err
+	}
+	return failed
+}
+
+// formatPendingReason describes the phase which is not met yet, or the first unmet phase before it if it is not started
+func formatPendingReason(status *PvcStatus, name PvcPhaseName) string {
+	if status.Phases[name].Status == "" {
+		for _, n := range phaseOrder {
+			if phase := status.Phases[n]; phase.Status != "" && !isPhaseMet(status, n) {
+				return fmt.Sprintf("%s not started, %s", name, formatPhaseReason(phase))
+			}
+		}
+		return fmt.Sprintf("%s not started", name)
+	}
+	return formatPhaseReason(status.Phases[name])
+}
+
+func formatPhaseReason(phase *PvcPhase) string {
+	if detail := formatPhaseDetail(phase); detail != "" {
+		return fmt.Sprintf("%s %s: %s", phase.Name, phase.Status, detail)
+	}
+	return fmt.Sprintf("%s %s", phase.Name, phase.Status)
+}
+
+// WaitPvcPhase waits until the phase of one pvc is met in the same way as WaitPvcs, progress is called
+// with the status and detail every time any phase up to the given one changes. It returns ErrWaitForFirstConsumer
+// if the pvc waits for the first consumer to be provisioned, since the phase after Provision may never be met.
+func (p *PvcContext) WaitPvcPhase(namespace, pvcname string, phase PvcPhaseName, timeout time.Duration,
+	progress func(name PvcPhaseName, status PvcPhaseStatus, detail string)) error {
+	if namespace == "" {
+		namespace = p.namespace
+	}
+
+	last := make(map[PvcPhaseName]string)
+	pending := "not checked yet"
+	var failed error
+	err := p.WatchPvcDetail(namespace, pvcname, timeout, func(status *PvcStatus, err error) bool {
+		if err != nil {
+			pending = err.Error()
+			return false
+		}
+		for _, n := range phaseOrder {
+			ph := status.Phases[n]
+			detail := formatPhaseDetail(ph)
+			if s := fmt.Sprintf("%s:%s", ph.Status, detail); ph.Status != "" && s != last[n] {
+				progress(n, ph.Status, detail)
+				last[n] = s
+			}
+			if n == phase {
+				break
+			}
+		}
+		if isPhaseMet(status, phase) {
+			return true
+		}
+		if f := hardFailure(status, phase); f != nil {
+			failed = &PvcFailedError{Namespace: namespace, Name: pvcname, Phase: f}
+			return true
+		}
+		if phase != PvcProvision && status.Phases[PvcProvision].waitForFirstConsumer {
+			failed = ErrWaitForFirstConsumer
+			return true
+		}
+		pending = formatPendingReason(status, phase)
+		return false
+	})
+	if err == ErrTimeout {
+		return &WaitTimeoutError{Pending: map[string]string{pvcname: pending}}
+	}
+	if err != nil {
+		return err
+	}
+	return failed
+}
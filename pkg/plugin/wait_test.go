@@ -0,0 +1,192 @@
+package plugin
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestHardFailure(t *testing.T) {
+	emptyClass := ""
+	newPvc := func(phase corev1.PersistentVolumeClaimPhase, conditions ...corev1.PersistentVolumeClaimConditionType) *corev1.PersistentVolumeClaim {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "data", UID: "uid-1"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				VolumeName:       "pv-1",
+				StorageClassName: &emptyClass,
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources:        corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")}},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Phase:    phase,
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		}
+		for _, c := range conditions {
+			pvc.Status.Conditions = append(pvc.Status.Conditions, corev1.PersistentVolumeClaimCondition{Type: c, Status: corev1.ConditionTrue, Message: "rpc error"})
+		}
+		return pvc
+	}
+	newPv := func(phase corev1.PersistentVolumePhase, claimUID string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: corev1.PersistentVolumeSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Capacity:    corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				ClaimRef:    &corev1.ObjectReference{Namespace: "team-a", Name: "data", UID: types.UID("uid-" + claimUID)},
+			},
+			Status: corev1.PersistentVolumeStatus{Phase: phase},
+		}
+	}
+	pending := newPvc(corev1.ClaimPending)
+	pending.Spec.VolumeName = ""
+	waitingPv, err := (&PvcContext{}).deducePhaseProvision(newClusterObjects(false), pending, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		phases []*PvcPhase
+		wait   PvcPhaseName
+		failed PvcPhaseName
+	}{
+		{
+			name:   "waiting for a pre-provisioned pv",
+			phases: []*PvcPhase{waitingPv},
+			wait:   PvcBind,
+		},
+		{
+			name:   "storageclass is not found",
+			phases: []*PvcPhase{{Name: PvcProvision, Status: PvcPhaseFail, Detail: "storageclass fast is not found"}},
+			wait:   PvcBind,
+		},
+		{
+			name:   "provisioning failed",
+			phases: []*PvcPhase{{Name: PvcProvision, Status: PvcPhaseFail, hard: true}},
+			wait:   PvcBind,
+			failed: PvcProvision,
+		},
+		{
+			name:   "pv is not found",
+			phases: []*PvcPhase{deducePhaseBind(newPvc(corev1.ClaimBound), nil)},
+			wait:   PvcBind,
+		},
+		{
+			name:   "pv is pre-bound but does not match",
+			phases: []*PvcPhase{deducePhasePreBind(pending, newPv(corev1.VolumeAvailable, "1"))},
+			wait:   PvcBind,
+		},
+		{
+			name:   "claimRef uid mismatch",
+			phases: []*PvcPhase{deducePhaseBind(newPvc(corev1.ClaimBound), newPv(corev1.VolumeBound, "0"))},
+			wait:   PvcMount,
+			failed: PvcBind,
+		},
+		{
+			name:   "pvc is lost",
+			phases: []*PvcPhase{deducePhaseBind(newPvc(corev1.ClaimLost), newPv(corev1.VolumeBound, "1"))},
+			wait:   PvcBind,
+			failed: PvcBind,
+		},
+		{
+			name:   "pv is failed",
+			phases: []*PvcPhase{deducePhaseBind(newPvc(corev1.ClaimBound), newPv(corev1.VolumeFailed, "1"))},
+			wait:   PvcBind,
+			failed: PvcBind,
+		},
+		{
+			name:   "bind failure after the waited phase",
+			phases: []*PvcPhase{deducePhaseBind(newPvc(corev1.ClaimLost), newPv(corev1.VolumeBound, "1"))},
+			wait:   PvcProvision,
+		},
+		{
+			name:   "attach failure is retried",
+			phases: []*PvcPhase{{Name: PvcAttach, Status: PvcPhaseFail, Detail: "node node-1 attach error: timeout"}},
+			wait:   PvcAttach,
+		},
+		{
+			name:   "controller resize error",
+			phases: []*PvcPhase{deducePhaseResize(newPvc(corev1.ClaimBound, pvcConditionControllerResizeError), newPv(corev1.VolumeBound, "1"), nil, nil)},
+			wait:   PvcResize,
+			failed: PvcResize,
+		},
+		{
+			name:   "node resize error",
+			phases: []*PvcPhase{deducePhaseResize(newPvc(corev1.ClaimBound, pvcConditionNodeResizeError), newPv(corev1.VolumeBound, "1"), nil, nil)},
+			wait:   PvcResize,
+			failed: PvcResize,
+		},
+		{
+			name:   "resize error does not fail waiting for mount",
+			phases: []*PvcPhase{deducePhaseResize(newPvc(corev1.ClaimBound, pvcConditionNodeResizeError), newPv(corev1.VolumeBound, "1"), nil, nil)},
+			wait:   PvcMount,
+		},
+		{
+			name: "resize failure event is retried",
+			phases: []*PvcPhase{deducePhaseResize(newPvc(corev1.ClaimBound), newPv(corev1.VolumeBound, "1"), nil,
+				[]*Event{{Phase: PvcResize, Type: corev1.EventTypeWarning, Reason: eventReasonVolumeResizeFailed}})},
+			wait: PvcResize,
+		},
+		{
+			name:   "resizing",
+			phases: []*PvcPhase{deducePhaseResize(newPvc(corev1.ClaimBound), newPv(corev1.VolumeBound, "1"), nil, nil)},
+			wait:   PvcResize,
+		},
+	}
+	for _, test := range tests {
+		status := newPvcStatus("data", "team-a")
+		for _, phase := range test.phases {
+			status.Phases[phase.Name] = phase
+		}
+		f := hardFailure(status, test.wait)
+		switch {
+		case f == nil && test.failed != "":
+			t.Errorf("%s: expected hard failure in %s, got nil", test.name, test.failed)
+		case f != nil && f.Name != test.failed:
+			t.Errorf("%s: expected hard failure in %q, got %s: %s", test.name, test.failed, f.Name, f.Detail)
+		}
+	}
+}
+
+func TestIsPhaseMet(t *testing.T) {
+	tests := []struct {
+		name  string
+		phase *PvcPhase
+		met   bool
+	}{
+		{
+			name:  "attached",
+			phase: deducePhaseAttach([]*Node{{Name: "node-1", Attached: true}}, map[string]struct{}{"node-1": {}}, true),
+			met:   true,
+		},
+		{
+			name:  "attach of unused pvc",
+			phase: deducePhaseAttach(nil, map[string]struct{}{}, true),
+		},
+		{
+			name:  "volume is not attachable",
+			phase: &PvcPhase{Name: PvcAttach, Status: PvcPhaseNotApplicable, Detail: "volume of plugin kubernetes.io/nfs is not attached to node"},
+			met:   true,
+		},
+		{
+			name:  "mount of unused pvc",
+			phase: deducePhaseMount(nil),
+		},
+		{
+			name:  "no expansion is requested",
+			phase: &PvcPhase{Name: PvcResize, Status: PvcPhaseNotApplicable},
+			met:   true,
+		},
+	}
+	for _, test := range tests {
+		status := newPvcStatus("data", "team-a")
+		status.Phases[test.phase.Name] = test.phase
+		if met := isPhaseMet(status, test.phase.Name); met != test.met {
+			t.Errorf("%s: expected met %v, got %v", test.name, test.met, met)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog"
+)
+
+// ErrTimeout is returned when the condition is not met before timeout
+var ErrTimeout = errors.New("timed out waiting for the condition")
+
+// changes in a short time are handled together, since one action always updates several objects,
+// e.g. binding updates both the pvc and the pv
+const watchSettleDelay = 200 * time.Millisecond
+
+// interval of polling the objects which are forbidden to watch
+const watchPollInterval = 2 * time.Second
+
+type watchFunc func(metav1.ListOptions) (watch.Interface, error)
+
+type objectWatch struct {
+	// description of the watched objects in logs and errors
+	resource string
+	fn       watchFunc
+	opts     metav1.ListOptions
+}
+
+// watchScope names the cluster scoped objects to watch besides the objects in the namespace,
+// each of them is watched with a field selector instead of watching all objects of the cluster
+type watchScope struct {
+	pvs   map[string]bool
+	nodes map[string]bool
+	vas   map[string]bool
+	// uids of the cluster scoped objects whose events are watched
+	uids map[types.UID]bool
+}
+
+func newWatchScope() *watchScope {
+	return &watchScope{
+		pvs:   make(map[string]bool),
+		nodes: make(map[string]bool),
+		vas:   make(map[string]bool),
+		uids:  make(map[types.UID]bool),
+	}
+}
+
+// addPvcStatus adds the pv, nodes and volumeattachments the status of pvc is deduced from
+func (s *watchScope) addPvcStatus(status *PvcStatus) {
+	if status == nil {
+		return
+	}
+	for _, pod := range status.Pods {
+		if pod.Node != "" {
+			s.nodes[pod.Node] = true
+		}
+	}
+	for _, node := range status.Nodes {
+		s.nodes[node.Name] = true
+		if node.VolumeAttachment != "" {
+			s.vas[node.VolumeAttachment] = true
+		}
+		if node.volumeAttachmentUID != "" {
+			s.uids[node.volumeAttachmentUID] = true
+		}
+	}
+
+	pv := status.PVStatus
+	if pv == nil {
+		return
+	}
+	s.pvs[pv.Name] = true
+	if pv.uid != "" {
+		s.uids[pv.uid] = true
+	}
+	// volumeattachments to be created for the pods which are not running yet
+	if pv.AttachedVolumeName != "" && pv.driver != "" {
+		for _, pod := range status.Pods {
+			if pod.Node != "" {
+				s.vas[csiAttachmentName(pv.VolumeHandle, pv.driver, pod.Node)] = true
+			}
+		}
+	}
+}
+
+// key tells whether the scope changes
+func (s *watchScope) key() string {
+	keys := make([]string, 0)
+	for name := range s.pvs {
+		keys = append(keys, "pv/"+name)
+	}
+	for name := range s.nodes {
+		keys = append(keys, "node/"+name)
+	}
+	for name := range s.vas {
+		keys = append(keys, "va/"+name)
+	}
+	for uid := range s.uids {
+		keys = append(keys, "uid/"+string(uid))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func (p *PvcContext) scopeWatches(s *watchScope) []objectWatch {
+	cli := p.k8scli
+	watches := make([]objectWatch, 0)
+	byName := func(resource string, fn watchFunc, names map[string]bool) {
+		for name := range names {
+			watches = append(watches, objectWatch{
+				resource: fmt.Sprintf("%s [%s]", resource, name),
+				fn:       fn,
+				opts:     metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String()},
+			})
+		}
+	}
+	byName("pv", cli.CoreV1().PersistentVolumes().Watch, s.pvs)
+	byName("node", cli.CoreV1().Nodes().Watch, s.nodes)
+	byName("volumeattachment", cli.StorageV1().VolumeAttachments().Watch, s.vas)
+	for uid := range s.uids {
+		watches = append(watches, objectWatch{
+			resource: fmt.Sprintf("events of [%s]", uid),
+			fn:       cli.CoreV1().Events(metav1.NamespaceAll).Watch,
+			opts:     metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("involvedObject.uid", string(uid)).String()},
+		})
+	}
+	return watches
+}
+
+// csiAttachmentName returns the name of VolumeAttachment created by attach/detach controller for csi volume
+func csiAttachmentName(volumeHandle, driver, node string) string {
+	return fmt.Sprintf("csi-%x", sha256.Sum256([]byte(volumeHandle+driver+node)))
+}
+
+// startWatches sends a notification on every change of the watched objects until stop is closed, objects
+// which are forbidden to watch are polled instead
+func (p *PvcContext) startWatches(watches []objectWatch, changes chan<- struct{}, errs chan<- error, stop <-chan struct{}) {
+	for _, ow := range watches {
+		go func(ow objectWatch) {
+			for {
+				w, err := ow.fn(ow.opts)
+				if err != nil {
+					if apierrors.IsNotFound(err) {
+						klog.V(2).Infof("%s are not served by apiserver, skip watching them", ow.resource)
+						return
+					}
+					if apierrors.IsForbidden(err) {
+						klog.V(2).Infof("watch %s is forbidden, poll every %v instead", ow.resource, watchPollInterval)
+						pollChanges(changes, stop)
+						return
+					}
+					select {
+					case errs <- fmt.Errorf("watch %s from kubernetes apiserver failed, err %v", ow.resource, err):
+					case <-stop:
+					}
+					return
+				}
+				if !forwardChanges(w, changes, stop) {
+					return
+				}
+				// the watch is closed by apiserver, start a new one
+				klog.V(2).Infof("watch of %s is closed, restart it", ow.resource)
+			}
+		}(ow)
+	}
+}
+
+// pollChanges sends a notification every watchPollInterval until stop is closed
+func pollChanges(changes chan<- struct{}, stop <-chan struct{}) {
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			notifyChange(changes)
+		}
+	}
+}
+
+func notifyChange(changes chan<- struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
+
+// forwardChanges returns false if stop is closed, or true if the watch is closed
+func forwardChanges(w watch.Interface, changes chan<- struct{}, stop <-chan struct{}) bool {
+	defer w.Stop()
+	for {
+		select {
+		case <-stop:
+			return false
+		case _, ok := <-w.ResultChan():
+			if !ok {
+				return true
+			}
+			notifyChange(changes)
+		}
+	}
+}
+
+// WatchPvcDetail deduces the status of pvc every time the related objects change, and calls handle with the status
+// or the error of deduction, it returns when handle returns true, or ErrTimeout if timeout is not zero and expires
+func (p *PvcContext) WatchPvcDetail(namespace, pvcname string, timeout time.Duration, handle func(*PvcStatus, error) bool) error {
+	if p.k8scli == nil {
+		return fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+	if namespace == "" {
+		namespace = p.namespace
+	}
+
+	return p.watchScopeUntil(namespace, timeout, func(scope *watchScope) bool {
+		status, err := p.GetPvcDetail(namespace, pvcname)
+		scope.addPvcStatus(status)
+		return handle(status, err)
+	})
+}
+
+// watchUntil calls check at first and then on every change of the pvcs, pods and events in the namespace,
+// until check returns true
+func (p *PvcContext) watchUntil(namespace string, timeout time.Duration, check func() bool) error {
+	return p.watchScopeUntil(namespace, timeout, func(*watchScope) bool {
+		return check()
+	})
+}
+
+// watchScopeUntil is watchUntil with the cluster scoped objects check adds into scope watched too,
+// the watches of them are restarted when the scope changes
+func (p *PvcContext) watchScopeUntil(namespace string, timeout time.Duration, check func(scope *watchScope) bool) error {
+	cli := p.k8scli
+	stop := make(chan struct{})
+	defer close(stop)
+	changes := make(chan struct{}, 1)
+	errs := make(chan error)
+	p.startWatches([]objectWatch{
+		{resource: "pvcs", fn: cli.CoreV1().PersistentVolumeClaims(namespace).Watch},
+		{resource: "pods", fn: cli.CoreV1().Pods(namespace).Watch},
+		{resource: "events", fn: cli.CoreV1().Events(namespace).Watch},
+	}, changes, errs, stop)
+
+	var (
+		scopeKey  string
+		scopeStop chan struct{}
+	)
+	defer func() {
+		if scopeStop != nil {
+			close(scopeStop)
+		}
+	}()
+	checkAndRescope := func() bool {
+		scope := newWatchScope()
+		if check(scope) {
+			return true
+		}
+		if key := scope.key(); key != scopeKey {
+			if scopeStop != nil {
+				close(scopeStop)
+			}
+			scopeKey, scopeStop = key, make(chan struct{})
+			p.startWatches(p.scopeWatches(scope), changes, errs, scopeStop)
+			// check again in case anything changes before the new watches start
+			notifyChange(changes)
+		}
+		return false
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	if checkAndRescope() {
+		return nil
+	}
+	for {
+		select {
+		case <-expired:
+			return ErrTimeout
+		case err := <-errs:
+			return err
+		case <-changes:
+			time.Sleep(watchSettleDelay)
+			// drop the changes happened during the delay
+			select {
+			case <-changes:
+			default:
+			}
+			if checkAndRescope() {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package plugin
+
+import (
+	"testing"
+)
+
+func TestWatchScopeAddPvcStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status *PvcStatus
+		key    string
+	}{
+		{
+			name: "not deduced",
+		},
+		{
+			name:   "pending pvc",
+			status: &PvcStatus{Pods: []*Pod{{Name: "web-0"}}},
+		},
+		{
+			name: "volume of in-tree plugin",
+			status: &PvcStatus{
+				PVStatus: &PVStatus{Name: "pv-1", AttachedVolumeName: "kubernetes.io/rbd/kube:image-1", uid: "uid-pv"},
+				Nodes:    []*Node{{Name: "node-1", Attached: true}},
+				Pods:     []*Pod{{Name: "web-0", Node: "node-2"}},
+			},
+			key: "node/node-1,node/node-2,pv/pv-1,uid/uid-pv",
+		},
+		{
+			name: "csi volume to be attached",
+			status: &PvcStatus{
+				PVStatus: &PVStatus{Name: "pv-1", VolumeHandle: "vol-1", AttachedVolumeName: "kubernetes.io/csi/csi.example.com^vol-1", driver: "csi.example.com"},
+				Nodes:    []*Node{{Name: "node-1", VolumeAttachment: "csi-old", volumeAttachmentUID: "uid-va"}},
+				Pods:     []*Pod{{Name: "web-0", Node: "node-2"}},
+			},
+			key: "node/node-1,node/node-2,pv/pv-1,uid/uid-va,va/" + csiAttachmentName("vol-1", "csi.example.com", "node-2") + ",va/csi-old",
+		},
+		{
+			name: "csi volume not attached",
+			status: &PvcStatus{
+				PVStatus: &PVStatus{Name: "pv-1", VolumeHandle: "vol-1", driver: "nfs.csi.k8s.io"},
+				Pods:     []*Pod{{Name: "web-0", Node: "node-2"}},
+			},
+			key: "node/node-2,pv/pv-1",
+		},
+	}
+	for _, test := range tests {
+		scope := newWatchScope()
+		scope.addPvcStatus(test.status)
+		if key := scope.key(); key != test.key {
+			t.Errorf("%s: expected scope %q, got %q", test.name, test.key, key)
+		}
+	}
+}
+
+func TestCsiAttachmentName(t *testing.T) {
+	// the name of volumeattachment created by attach/detach controller
+	expected := "csi-b74c7fc125891ffa0d0f2e0d645733e3fae7fba3066e76500a771ea08be9da28"
+	if name := csiAttachmentName("vol-1", "csi.example.com", "node-1"); name != expected {
+		t.Errorf("expected volumeattachment name %q, got %q", expected, name)
+	}
+}
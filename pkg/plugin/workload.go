@@ -0,0 +1,423 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindDaemonSet   = "DaemonSet"
+	KindReplicaSet  = "ReplicaSet"
+	KindJob         = "Job"
+	KindCronJob     = "CronJob"
+)
+
+// cronjobs of batch/v1, it is not in the api of this version so it is read by the dynamic client
+var cronJobResource = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}
+
+// Workload is a controller whose pod template references pvcs
+type Workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// pvcs referenced by the volumes of pod template
+	Claims []string
+	// pvcs expected from volumeClaimTemplates of StatefulSet, named <template>-<statefulset>-<ordinal>
+	TemplateClaims []string
+	// desired replicas, nil for workloads which can not be scaled, e.g. DaemonSet
+	Replicas *int32
+	// the workload is managed by another one, e.g. ReplicaSet of Deployment
+	Controlled  bool
+	Annotations map[string]string
+
+	// template name of each pvc in TemplateClaims
+	claimTemplates map[string]string
+}
+
+func newWorkload(kind string, meta metav1.ObjectMeta, spec *corev1.PodSpec) *Workload {
+	w := &Workload{
+		Kind:        kind,
+		Namespace:   meta.Namespace,
+		Name:        meta.Name,
+		Claims:      make([]string, 0),
+		Controlled:  metav1.GetControllerOf(&meta) != nil,
+		Annotations: meta.Annotations,
+	}
+	for _, vol := range spec.Volumes {
+		if vol.PersistentVolumeClaim != nil {
+			w.Claims = append(w.Claims, vol.PersistentVolumeClaim.ClaimName)
+		}
+	}
+	return w
+}
+
+// replicasOrDefault returns the replicas, which is 1 by default
+func replicasOrDefault(replicas *int32) *int32 {
+	r := int32(1)
+	if replicas != nil {
+		r = *replicas
+	}
+	return &r
+}
+
+func NewWorkloadFromDeployment(d *appsv1.Deployment) *Workload {
+	w := newWorkload(KindDeployment, d.ObjectMeta, &d.Spec.Template.Spec)
+	w.Replicas = replicasOrDefault(d.Spec.Replicas)
+	return w
+}
+
+func NewWorkloadFromStatefulSet(s *appsv1.StatefulSet) *Workload {
+	w := newWorkload(KindStatefulSet, s.ObjectMeta, &s.Spec.Template.Spec)
+	w.Replicas = replicasOrDefault(s.Spec.Replicas)
+	replicas := *w.Replicas
+	w.claimTemplates = make(map[string]string)
+	for _, tpl := range s.Spec.VolumeClaimTemplates {
+		for i := int32(0); i < replicas; i++ {
+			claim := fmt.Sprintf("%s-%s-%d", tpl.Name, s.Name, i)
+			w.TemplateClaims = append(w.TemplateClaims, claim)
+			w.claimTemplates[claim] = tpl.Name
+		}
+	}
+	return w
+}
+
+func NewWorkloadFromDaemonSet(d *appsv1.DaemonSet) *Workload {
+	return newWorkload(KindDaemonSet, d.ObjectMeta, &d.Spec.Template.Spec)
+}
+
+func NewWorkloadFromReplicaSet(r *appsv1.ReplicaSet) *Workload {
+	w := newWorkload(KindReplicaSet, r.ObjectMeta, &r.Spec.Template.Spec)
+	w.Replicas = replicasOrDefault(r.Spec.Replicas)
+	return w
+}
+
+func NewWorkloadFromJob(j *batchv1.Job) *Workload {
+	return newWorkload(KindJob, j.ObjectMeta, &j.Spec.Template.Spec)
+}
+
+func NewWorkloadFromCronJob(c *batchv1beta1.CronJob) *Workload {
+	return newWorkload(KindCronJob, c.ObjectMeta, &c.Spec.JobTemplate.Spec.Template.Spec)
+}
+
+// AllClaims returns the pvcs referenced by pod template and expected from volumeClaimTemplates
+func (w *Workload) AllClaims() []string {
+	return append(append([]string{}, w.Claims...), w.TemplateClaims...)
+}
+
+// listWorkloads lists all workloads which may reference pvcs in the namespace,
+// namespace should be empty for all namespaces
+func (p *PvcContext) listWorkloads(namespace string) ([]*Workload, error) {
+	cli := p.k8scli
+	workloads := make([]*Workload, 0)
+
+	deployments, err := cli.AppsV1().Deployments(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list deployments from kubernetes apiserver failed, err %v", err)
+	}
+	for i := range deployments.Items {
+		workloads = append(workloads, NewWorkloadFromDeployment(&deployments.Items[i]))
+	}
+
+	statefulsets, err := cli.AppsV1().StatefulSets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list statefulsets from kubernetes apiserver failed, err %v", err)
+	}
+	for i := range statefulsets.Items {
+		workloads = append(workloads, NewWorkloadFromStatefulSet(&statefulsets.Items[i]))
+	}
+
+	daemonsets, err := cli.AppsV1().DaemonSets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list daemonsets from kubernetes apiserver failed, err %v", err)
+	}
+	for i := range daemonsets.Items {
+		workloads = append(workloads, NewWorkloadFromDaemonSet(&daemonsets.Items[i]))
+	}
+
+	replicasets, err := cli.AppsV1().ReplicaSets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list replicasets from kubernetes apiserver failed, err %v", err)
+	}
+	for i := range replicasets.Items {
+		workloads = append(workloads, NewWorkloadFromReplicaSet(&replicasets.Items[i]))
+	}
+
+	jobs, err := cli.BatchV1().Jobs(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list jobs from kubernetes apiserver failed, err %v", err)
+	}
+	for i := range jobs.Items {
+		workloads = append(workloads, NewWorkloadFromJob(&jobs.Items[i]))
+	}
+
+	cronjobs, err := p.listCronJobs(namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cronjobs {
+		workloads = append(workloads, NewWorkloadFromCronJob(&cronjobs[i]))
+	}
+
+	return workloads, nil
+}
+
+// listCronJobs lists cronjobs of batch/v1 which is served since kubernetes 1.21, and falls back to
+// batch/v1beta1 which is removed in 1.25. batch/v1 is not in the api of this version, so it is listed
+// by the dynamic client and converted to batch/v1beta1 whose pod template is the same.
+func (p *PvcContext) listCronJobs(namespace string) ([]batchv1beta1.CronJob, error) {
+	list, err := p.dyncli.Resource(cronJobResource).Namespace(namespace).List(metav1.ListOptions{})
+	if err == nil {
+		cronjobs := make([]batchv1beta1.CronJob, len(list.Items))
+		for i := range list.Items {
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(list.Items[i].Object, &cronjobs[i]); err != nil {
+				return nil, fmt.Errorf("decode cronjob [%s/%s] failed, err: %v", list.Items[i].GetNamespace(), list.Items[i].GetName(), err)
+			}
+		}
+		return cronjobs, nil
+	}
+	if !isNotServed(err) {
+		return nil, fmt.Errorf("list cronjobs from kubernetes apiserver failed, err %v", err)
+	}
+
+	betaList, err := p.k8scli.BatchV1beta1().CronJobs(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list cronjobs from kubernetes apiserver failed, err %v", err)
+	}
+	return betaList.Items, nil
+}
+
+// getCronJob gets the cronjob the same way as listCronJobs, batch/v1 first and then batch/v1beta1
+func (p *PvcContext) getCronJob(namespace, name string) (*batchv1beta1.CronJob, error) {
+	u, err := p.dyncli.Resource(cronJobResource).Namespace(namespace).Get(name, metav1.GetOptions{})
+	if err == nil {
+		cronjob := &batchv1beta1.CronJob{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, cronjob); err != nil {
+			return nil, fmt.Errorf("decode cronjob [%s/%s] failed, err: %v", namespace, name, err)
+		}
+		return cronjob, nil
+	}
+	if !isNotServed(err) {
+		return nil, err
+	}
+
+	cronjob, betaErr := p.k8scli.BatchV1beta1().CronJobs(namespace).Get(name, metav1.GetOptions{})
+	if betaErr != nil {
+		// batch/v1beta1 is not served either, the cronjob is not found in batch/v1
+		if isNotServed(betaErr) {
+			return nil, err
+		}
+		return nil, betaErr
+	}
+	return cronjob, nil
+}
+
+// isNotServed tells whether the error may be caused by the group version not served by apiserver
+func isNotServed(err error) bool {
+	return apierrors.IsNotFound(err) || meta.IsNoMatchError(err)
+}
+
+// workloadKinds maps the kind, its plural and short names accepted by --owner to the kind
+var workloadKinds = map[string]string{
+	"deployment":   KindDeployment,
+	"deployments":  KindDeployment,
+	"deploy":       KindDeployment,
+	"statefulset":  KindStatefulSet,
+	"statefulsets": KindStatefulSet,
+	"sts":          KindStatefulSet,
+	"daemonset":    KindDaemonSet,
+	"daemonsets":   KindDaemonSet,
+	"ds":           KindDaemonSet,
+	"replicaset":   KindReplicaSet,
+	"replicasets":  KindReplicaSet,
+	"rs":           KindReplicaSet,
+	"job":          KindJob,
+	"jobs":         KindJob,
+	"cronjob":      KindCronJob,
+	"cronjobs":     KindCronJob,
+	"cj":           KindCronJob,
+}
+
+// ParseOwner parses owner in the form of <kind>/<name>, e.g. deployment/foo or sts/bar
+func ParseOwner(owner string) (kind, name string, err error) {
+	parts := strings.SplitN(owner, "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid owner %q, should be in the form of <kind>/<name>", owner)
+	}
+	kind, ok := workloadKinds[strings.ToLower(parts[0])]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported owner kind %q, should be one of: deployment|statefulset|daemonset|replicaset|job|cronjob", parts[0])
+	}
+	return kind, parts[1], nil
+}
+
+// GetWorkload gets the workload of the kind returned by ParseOwner
+func (p *PvcContext) GetWorkload(namespace, kind, name string) (*Workload, error) {
+	if p.k8scli == nil {
+		return nil, fmt.Errorf("PvcContext.k8scli should not be nil")
+	}
+
+	var (
+		w   *Workload
+		err error
+	)
+	cli := p.k8scli
+	switch kind {
+	case KindDeployment:
+		var d *appsv1.Deployment
+		if d, err = cli.AppsV1().Deployments(namespace).Get(name, metav1.GetOptions{}); err == nil {
+			w = NewWorkloadFromDeployment(d)
+		}
+	case KindStatefulSet:
+		var s *appsv1.StatefulSet
+		if s, err = cli.AppsV1().StatefulSets(namespace).Get(name, metav1.GetOptions{}); err == nil {
+			w = NewWorkloadFromStatefulSet(s)
+		}
+	case KindDaemonSet:
+		var d *appsv1.DaemonSet
+		if d, err = cli.AppsV1().DaemonSets(namespace).Get(name, metav1.GetOptions{}); err == nil {
+			w = NewWorkloadFromDaemonSet(d)
+		}
+	case KindReplicaSet:
+		var r *appsv1.ReplicaSet
+		if r, err = cli.AppsV1().ReplicaSets(namespace).Get(name, metav1.GetOptions{}); err == nil {
+			w = NewWorkloadFromReplicaSet(r)
+		}
+	case KindJob:
+		var j *batchv1.Job
+		if j, err = cli.BatchV1().Jobs(namespace).Get(name, metav1.GetOptions{}); err == nil {
+			w = NewWorkloadFromJob(j)
+		}
+	case KindCronJob:
+		var c *batchv1beta1.CronJob
+		if c, err = p.getCronJob(namespace, name); err == nil {
+			w = NewWorkloadFromCronJob(c)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported workload kind %q", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get info about %s [%s/%s] failed, err: %v", strings.ToLower(kind), namespace, name, err)
+	}
+	return w, nil
+}
+
+type ClaimState string
+
+const (
+	ClaimBound   ClaimState = "Bound"
+	ClaimUnbound ClaimState = "Unbound"
+	ClaimMissing ClaimState = "Missing"
+)
+
+// WorkloadClaim is a pvc expected by the workload
+type WorkloadClaim struct {
+	Name string
+	// empty if the pvc is referenced by the pod template directly
+	Template string
+	State    ClaimState
+}
+
+// ListPvcsByOwner lists the existing pvcs expected by the workload, together with the state of every expected pvc,
+// selectors are matched by the plugin itself and only filter the existing pvcs
+func (p *PvcContext) ListPvcsByOwner(owner string, opt ListPvcsOption) ([]corev1.PersistentVolumeClaim, []*WorkloadClaim, error) {
+	pvcs := make([]corev1.PersistentVolumeClaim, 0)
+	kind, name, err := ParseOwner(owner)
+	if err != nil {
+		return pvcs, nil, err
+	}
+
+	matcher, err := newSelectorMatcher(opt.LabelSelector, opt.FieldSelector)
+	if err != nil {
+		return pvcs, nil, err
+	}
+
+	w, err := p.GetWorkload(p.namespace, kind, name)
+	if err != nil {
+		return pvcs, nil, err
+	}
+
+	claims := make([]*WorkloadClaim, 0)
+	for _, claim := range w.Claims {
+		claims = append(claims, &WorkloadClaim{Name: claim})
+	}
+	for _, claim := range w.TemplateClaims {
+		claims = append(claims, &WorkloadClaim{Name: claim, Template: w.claimTemplates[claim]})
+	}
+
+	seen := make(map[string]bool)
+	for _, claim := range claims {
+		pvc, err := p.k8scli.CoreV1().PersistentVolumeClaims(p.namespace).Get(claim.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				claim.State = ClaimMissing
+				continue
+			}
+			return pvcs, nil, fmt.Errorf("get pvc [%v/%v] info from kubernetes apiserver failed, err: %v", p.namespace, claim.Name, err)
+		}
+		claim.State = ClaimBound
+		if pvc.Status.Phase != corev1.ClaimBound {
+			claim.State = ClaimUnbound
+		}
+		// the same pvc may be referenced by several volumes of the pod template
+		if !seen[pvc.Name] && matcher.matches(pvc) {
+			pvcs = append(pvcs, *pvc)
+		}
+		seen[pvc.Name] = true
+	}
+
+	return pvcs, claims, nil
+}
+
+// References returns true if the pod template or volumeClaimTemplates of workload reference the pvc
+func (w *Workload) References(pvcname string) bool {
+	for _, claim := range w.AllClaims() {
+		if claim == pvcname {
+			return true
+		}
+	}
+	return false
+}
+
+// scaleWorkload sets the replicas of workload together with the annotations, annotation with nil value is removed
+func (p *PvcContext) scaleWorkload(w *Workload, replicas int32, annotations map[string]*string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+		"spec":     map[string]interface{}{"replicas": replicas},
+	})
+	if err != nil {
+		return err
+	}
+
+	cli := p.k8scli
+	switch w.Kind {
+	case KindDeployment:
+		_, err = cli.AppsV1().Deployments(w.Namespace).Patch(w.Name, types.MergePatchType, patch)
+	case KindStatefulSet:
+		_, err = cli.AppsV1().StatefulSets(w.Namespace).Patch(w.Name, types.MergePatchType, patch)
+	case KindReplicaSet:
+		_, err = cli.AppsV1().ReplicaSets(w.Namespace).Patch(w.Name, types.MergePatchType, patch)
+	default:
+		return fmt.Errorf("%s [%s/%s] can not be scaled", strings.ToLower(w.Kind), w.Namespace, w.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("scale %s [%s/%s] to %d failed, err: %v", strings.ToLower(w.Kind), w.Namespace, w.Name, replicas, err)
+	}
+	return nil
+}
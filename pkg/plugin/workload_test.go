@@ -0,0 +1,43 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsNotServed(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		notServed bool
+	}{
+		{
+			name:      "group version is not served",
+			err:       apierrors.NewNotFound(schema.GroupResource{}, ""),
+			notServed: true,
+		},
+		{
+			name:      "cronjob is not found",
+			err:       apierrors.NewNotFound(schema.GroupResource{Group: "batch", Resource: "cronjobs"}, "backup"),
+			notServed: true,
+		},
+		{
+			name:      "no match",
+			err:       &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "batch", Kind: "CronJob"}, SearchedVersions: []string{"v1"}},
+			notServed: true,
+		},
+		{
+			name: "forbidden",
+			err:  apierrors.NewForbidden(schema.GroupResource{Group: "batch", Resource: "cronjobs"}, "backup", fmt.Errorf("denied")),
+		},
+	}
+	for _, test := range tests {
+		if notServed := isNotServed(test.err); notServed != test.notServed {
+			t.Errorf("%s: expected not served %v, got %v", test.name, test.notServed, notServed)
+		}
+	}
+}